@@ -1,56 +1,242 @@
 package bus
 
 // This is the Nes Emulator BUS
-// This bus have 64KB of addressable space
+// The CPU sees 64KB of addressable space laid out as:
+//
+//	$0000-$1FFF 2KB internal RAM, mirrored every 2KB
+//	$2000-$3FFF PPU registers, mirrored every 8 bytes
+//	$4000-$401F APU and I/O registers
+//	$4020-$FFFF Cartridge space
+
+// Address ranges of the NES CPU memory map
+const (
+	RAMStart       uint16 = 0x0000
+	RAMEnd         uint16 = 0x1FFF
+	RAMMask        uint16 = 0x07FF
+	PPUStart       uint16 = 0x2000
+	PPUEnd         uint16 = 0x3FFF
+	PPUMask        uint16 = 0x0007
+	IOStart        uint16 = 0x4000
+	IOEnd          uint16 = 0x401F
+	CartridgeStart uint16 = 0x4020
+	CartridgeEnd   uint16 = 0xFFFF
+
+	// Writing a page number here copies that page into PPU OAM
+	OAMDMA  uint16 = 0x4014
+	OAMData uint16 = 0x2004
+)
+
+// IRQ sources sharing the level-triggered CPU IRQ line
+const (
+	IRQMapper uint8 = 1 << iota
+	IRQFrameCounter
+	IRQDMC
+)
+
+// Device is anything that answers CPU reads and writes on the bus.
+// The address handed to a device is already masked by the range it was
+// attached with, so the PPU sees $0000-$0007 while the cartridge sees the
+// full CPU address.
+type Device interface {
+	CPURead(addr uint16, bReadOnly bool) byte
+	CPUWrite(addr uint16, data byte)
+}
+
+// CPU is the processor driven by the system clock
+type CPU interface {
+	Clock()
+	SetNMI(active bool)
+	SetIRQ(source uint8, active bool)
+}
+
+// PPU is the picture processor, clocked three times per CPU cycle. Its
+// NMI output is wired to the CPU NMI line.
+type PPU interface {
+	Device
+	Clock()
+	NMI() bool
+}
+
+// Cartridge sits at $4020-$FFFF, it sees every CPU cycle and may hold
+// the IRQ line
+type Cartridge interface {
+	Device
+	Clock()
+	IRQ() bool
+}
+
+// Snooper is a cartridge that also watches CPU writes to the PPU
+// registers, the way MMC5 follows the sprite size and rendering state.
+// The register is handed over masked to $0000-$0007.
+type Snooper interface {
+	SnoopPPU(reg uint16, data byte)
+}
+
+type region struct {
+	start  uint16
+	end    uint16
+	mask   uint16
+	device Device
+}
 
 type BUS struct {
-	nSystemClockCounter uint16
-	data                []byte
+	nSystemClockCounter uint64
+	cpuRam              [2048]byte
+	regions             []region
+	cpu                 CPU
+	ppu                 PPU
+	cartridge           Cartridge
+	snooper             Snooper
+
+	dmaPage     byte
+	dmaAddr     byte
+	dmaData     byte
+	dmaDummy    bool
+	dmaTransfer bool
 }
 
 func New() *BUS {
 	return &BUS{
 		nSystemClockCounter: 0x00,
-		data:                make([]byte, 0xFFFF),
 	}
 }
 
+// Attach maps a device into $start-$end. Every access in the range is
+// masked with mask before it reaches the device, which is how mirrored
+// registers are expressed. Ranges attached first take priority.
+func (b *BUS) Attach(start, end, mask uint16, device Device) {
+	b.regions = append(b.regions, region{
+		start:  start,
+		end:    end,
+		mask:   mask,
+		device: device,
+	})
+}
+
+func (b *BUS) ConnectCPU(cpu CPU) {
+	b.cpu = cpu
+}
+
+// ConnectPPU attaches the PPU registers at $2000-$3FFF and lets the
+// system clock drive it
+func (b *BUS) ConnectPPU(ppu PPU) {
+	b.ppu = ppu
+	b.Attach(PPUStart, PPUEnd, PPUMask, ppu)
+}
+
+// ConnectCartridge attaches the cartridge at $4020-$FFFF and wires its
+// IRQ output to the CPU
+func (b *BUS) ConnectCartridge(cartridge Cartridge) {
+	b.cartridge = cartridge
+	b.snooper, _ = cartridge.(Snooper)
+	b.Attach(CartridgeStart, CartridgeEnd, 0xFFFF, cartridge)
+}
+
+// Clock advances the system by one PPU dot, the CPU runs every third one
+func (b *BUS) Clock() {
+	if b.ppu != nil {
+		b.ppu.Clock()
+	}
+
+	if b.nSystemClockCounter%3 == 0 && b.cartridge != nil {
+		b.cartridge.Clock()
+	}
+
+	if b.cpu != nil {
+		if b.nSystemClockCounter%3 == 0 {
+			if b.dmaTransfer {
+				b.clockDMA()
+			} else {
+				b.cpu.Clock()
+			}
+		}
+		if b.ppu != nil {
+			b.cpu.SetNMI(b.ppu.NMI())
+		}
+		if b.cartridge != nil {
+			b.cpu.SetIRQ(IRQMapper, b.cartridge.IRQ())
+		}
+	}
+
+	b.nSystemClockCounter++
+}
+
 func (b *BUS) Write(addr uint16, data byte) {
-	b.data[addr] = data
+	if addr <= RAMEnd {
+		b.cpuRam[addr&RAMMask] = data
+		return
+	}
+
+	if addr == OAMDMA {
+		b.dmaPage = data
+		b.dmaAddr = 0x00
+		b.dmaDummy = true
+		b.dmaTransfer = true
+		return
+	}
+
+	if r := b.find(addr); r != nil {
+		r.device.CPUWrite(addr&r.mask, data)
+	}
+
+	if b.snooper != nil && addr >= PPUStart && addr <= PPUEnd {
+		b.snooper.SnoopPPU(addr&PPUMask, data)
+	}
 }
 
 func (b *BUS) Read(addr uint16) byte {
-	return b.data[addr]
+	return b.read(addr, false)
 }
 
-// func CPUWrite(addr uint16, data byte) {
+// Peek reads the bus without triggering the side effects of a real read,
+// such as clearing the PPU vblank flag
+func (b *BUS) Peek(addr uint16) byte {
+	return b.read(addr, true)
+}
+
+func (b *BUS) read(addr uint16, bReadOnly bool) byte {
+	if addr <= RAMEnd {
+		return b.cpuRam[addr&RAMMask]
+	}
 
-// 	if cartridge.CPUWrite(addr, data) {
-// 		return
-// 	}
+	if r := b.find(addr); r != nil {
+		return r.device.CPURead(addr&r.mask, bReadOnly)
+	}
+
+	return 0x00
+}
 
-// 	if addr <= 0xFFFF {
-// 		cpuRam[addr&0x07FF] = data
-// 		return
-// 	}
+// clockDMA runs one CPU cycle of an OAM DMA, the CPU is halted meanwhile.
+// The transfer waits for an even cycle, then alternates reading a byte
+// from the page and writing it to OAMDATA, 513 or 514 cycles in total.
+func (b *BUS) clockDMA() {
+	cpuCycle := b.nSystemClockCounter / 3
 
-// 	if addr >= 0x2000 && addr <= 0x3FFF {
-// 		ppu.CPUWrite(addr&0x0007, data)
-// 		return
-// 	}
-// }
+	if b.dmaDummy {
+		if cpuCycle%2 == 1 {
+			b.dmaDummy = false
+		}
+		return
+	}
 
-// func CPURead(addr uint16, bReadOnly bool) byte {
-// 	if cartridge.CPURead(addr, bReadOnly) {
-// 		return 0x00
-// 	}
-// 	if addr <= 0x1FFF {
-// 		return cpuRam[addr&0x07FF]
-// 	}
+	if cpuCycle%2 == 0 {
+		b.dmaData = b.Read(uint16(b.dmaPage)<<8 | uint16(b.dmaAddr))
+		return
+	}
 
-// 	if addr >= 0x2000 && addr <= 0x3FFF {
-// 		return ppu.CPURead(addr&0x07FF, bReadOnly)
-// 	}
+	b.Write(OAMData, b.dmaData)
+	b.dmaAddr++
+	if b.dmaAddr == 0x00 {
+		b.dmaTransfer = false
+		b.dmaDummy = true
+	}
+}
 
-// 	return 0x00
-// }
+func (b *BUS) find(addr uint16) *region {
+	for i := range b.regions {
+		if addr >= b.regions[i].start && addr <= b.regions[i].end {
+			return &b.regions[i]
+		}
+	}
+	return nil
+}
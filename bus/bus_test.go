@@ -0,0 +1,180 @@
+package bus
+
+import (
+	"testing"
+
+	"github.com/patrickn2/gonesemulator/input"
+)
+
+// recorder is a device that remembers the last access it saw
+type recorder struct {
+	readAddr  uint16
+	writeAddr uint16
+	data      byte
+	reads     int
+	peeks     int
+}
+
+func (r *recorder) CPURead(addr uint16, bReadOnly bool) byte {
+	r.readAddr = addr
+	if bReadOnly {
+		r.peeks++
+	} else {
+		r.reads++
+	}
+	return byte(addr)
+}
+
+func (r *recorder) CPUWrite(addr uint16, data byte) {
+	r.writeAddr = addr
+	r.data = data
+}
+
+// recordingPPU is a recorder that also satisfies the PPU interface
+type recordingPPU struct {
+	recorder
+}
+
+func (p *recordingPPU) Clock()    {}
+func (p *recordingPPU) NMI() bool { return false }
+
+// recordingCartridge is a recorder that also satisfies the Cartridge
+// interface
+type recordingCartridge struct {
+	recorder
+}
+
+func (c *recordingCartridge) Clock()    {}
+func (c *recordingCartridge) IRQ() bool { return false }
+
+func TestRAMMirroring(t *testing.T) {
+	b := New()
+	tests := []struct {
+		write uint16
+		read  uint16
+	}{
+		{0x0000, 0x0800},
+		{0x0001, 0x1001},
+		{0x07FF, 0x1FFF},
+		{0x1234, 0x0234},
+		{0x1800, 0x0000},
+	}
+	for i, tt := range tests {
+		data := byte(0x10 + i)
+		b.Write(tt.write, data)
+		if got := b.Read(tt.read); got != data {
+			t.Errorf("write $%04X read $%04X: got %02X, want %02X", tt.write, tt.read, got, data)
+		}
+	}
+}
+
+func TestPPURegisterMirroring(t *testing.T) {
+	b := New()
+	ppu := &recordingPPU{}
+	b.ConnectPPU(ppu)
+	tests := []struct {
+		addr uint16
+		reg  uint16
+	}{
+		{0x2000, 0x0000},
+		{0x2007, 0x0007},
+		{0x2008, 0x0000},
+		{0x200F, 0x0007},
+		{0x3456, 0x0006},
+		{0x3FFF, 0x0007},
+	}
+	for _, tt := range tests {
+		b.Write(tt.addr, 0xA5)
+		if ppu.writeAddr != tt.reg || ppu.data != 0xA5 {
+			t.Errorf("write $%04X: device saw $%04X=%02X, want $%04X=A5", tt.addr, ppu.writeAddr, ppu.data, tt.reg)
+		}
+		b.Read(tt.addr)
+		if ppu.readAddr != tt.reg {
+			t.Errorf("read $%04X: device saw $%04X, want $%04X", tt.addr, ppu.readAddr, tt.reg)
+		}
+	}
+}
+
+func TestControllerPorts(t *testing.T) {
+	b := New()
+	pads := input.New()
+	b.Attach(input.Port1, input.Port2, 0xFFFF, pads)
+	pads.SetButtons(0, input.ButtonA|input.ButtonStart)
+	pads.SetButtons(1, input.ButtonB)
+
+	b.Write(input.Port1, 0x01)
+	b.Write(input.Port1, 0x00)
+	tests := []struct {
+		port uint16
+		want []byte
+	}{
+		{input.Port1, []byte{1, 0, 0, 1, 0, 0, 0, 0, 1}},
+		{input.Port2, []byte{0, 1, 0, 0, 0, 0, 0, 0, 1}},
+	}
+	for _, tt := range tests {
+		for i, want := range tt.want {
+			if got := b.Read(tt.port); got != want {
+				t.Errorf("$%04X read %d: got %d, want %d", tt.port, i, got, want)
+			}
+		}
+	}
+}
+
+func TestCartridgeAddress(t *testing.T) {
+	b := New()
+	cart := &recordingCartridge{}
+	b.ConnectCartridge(cart)
+	tests := []uint16{0x4020, 0x5000, 0x6000, 0x8000, 0xC123, 0xFFFC, 0xFFFF}
+	for _, addr := range tests {
+		b.Write(addr, 0x5A)
+		if cart.writeAddr != addr {
+			t.Errorf("write $%04X: cartridge saw $%04X", addr, cart.writeAddr)
+		}
+		if got := b.Read(addr); got != byte(addr) || cart.readAddr != addr {
+			t.Errorf("read $%04X: cartridge saw $%04X and returned %02X", addr, cart.readAddr, got)
+		}
+	}
+}
+
+func TestUnmappedReadsZero(t *testing.T) {
+	b := New()
+	for _, addr := range []uint16{0x2000, 0x4000, 0x4016, 0x8000, 0xFFFF} {
+		if got := b.Read(addr); got != 0x00 {
+			t.Errorf("read $%04X: got %02X, want 00", addr, got)
+		}
+	}
+}
+
+func TestPeek(t *testing.T) {
+	b := New()
+	ppu := &recordingPPU{}
+	cart := &recordingCartridge{}
+	pads := input.New()
+	b.ConnectPPU(ppu)
+	b.Attach(input.Port1, input.Port2, 0xFFFF, pads)
+	b.ConnectCartridge(cart)
+
+	b.Peek(0x2002)
+	b.Peek(0xFFFF)
+	if ppu.reads != 0 || ppu.peeks != 1 {
+		t.Errorf("PPU: %d reads and %d peeks, want 0 and 1", ppu.reads, ppu.peeks)
+	}
+	if cart.reads != 0 || cart.peeks != 1 {
+		t.Errorf("cartridge: %d reads and %d peeks, want 0 and 1", cart.reads, cart.peeks)
+	}
+
+	pads.SetButtons(0, input.ButtonA)
+	b.Write(input.Port1, 0x01)
+	b.Write(input.Port1, 0x00)
+	for i := 0; i < 3; i++ {
+		if got := b.Peek(input.Port1); got != 1 {
+			t.Fatalf("peek %d: got %d, want 1", i, got)
+		}
+	}
+	if got := b.Read(input.Port1); got != 1 {
+		t.Errorf("read after peeks: got %d, want 1", got)
+	}
+	if got := b.Read(input.Port1); got != 0 {
+		t.Errorf("second read: got %d, want 0", got)
+	}
+}
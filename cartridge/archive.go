@@ -0,0 +1,158 @@
+package cartridge
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoROMInArchive = errors.New("cartridge: no ROM found in archive")
+	ErrEntryNotFound  = errors.New("cartridge: entry not found in archive")
+	ErrImageTooLarge  = errors.New("cartridge: image too large")
+)
+
+// romExtensions are the archive entries picked when no entry is named
+var romExtensions = []string{".nes", ".unf", ".fds", ".nsf"}
+
+// maxImageSize bounds what is decompressed into memory, the largest
+// cartridges are a few megabytes
+const maxImageSize = 64 << 20
+
+// Options tune how Open finds and prepares the image
+type Options struct {
+	// Entry names the file to load from a zip archive. When empty the
+	// only, or else the first, entry with a ROM extension is used.
+	Entry string
+
+	// Patches are IPS, UPS or BPS files applied in order to the image
+	// before the header is parsed. AutoPatch also applies the .ips, .ups
+	// and .bps files next to the ROM sharing its basename.
+	Patches   []string
+	AutoPatch bool
+
+	// SaveDir holds the .sav files of battery backed cartridges, next to
+	// the ROM when empty. Saves are written every AutosaveInterval, zero
+	// means DefaultAutosaveInterval and a negative value only saves on
+	// Close.
+	SaveDir          string
+	AutosaveInterval time.Duration
+}
+
+// Open loads a cartridge from disk. Plain images, zip archives and gzip
+// files are told apart by their content, not their extension.
+func Open(fileLocation string, opts Options) (*Cartridge, error) {
+	data, err := readImage(fileLocation, opts)
+	if err != nil {
+		return nil, err
+	}
+	data, err = applyPatches(data, patchFiles(fileLocation, opts))
+	if err != nil {
+		return nil, err
+	}
+	cart, err := Load(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if err := cart.attachSave(fileLocation, opts); err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+// Private Methods
+
+func readImage(fileLocation string, opts Options) ([]byte, error) {
+	data, err := os.ReadFile(fileLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")), bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		return readZip(data, opts.Entry)
+	case bytes.HasPrefix(data, []byte{0x1F, 0x8B}):
+		return readGzip(data)
+	}
+	return data, nil
+}
+
+func readZip(data []byte, entry string) ([]byte, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("cartridge: reading zip: %w", err)
+	}
+
+	file, err := zipEntry(archive, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("cartridge: reading %s: %w", file.Name, err)
+	}
+	defer rc.Close()
+	return readAll(rc, file.Name)
+}
+
+// zipEntry picks the named entry, or the first one that looks like a ROM
+func zipEntry(archive *zip.Reader, entry string) (*zip.File, error) {
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		if entry != "" {
+			if file.Name == entry || path.Base(file.Name) == entry {
+				return file, nil
+			}
+			continue
+		}
+		ext := strings.ToLower(path.Ext(file.Name))
+		for _, romExt := range romExtensions {
+			if ext == romExt {
+				return file, nil
+			}
+		}
+	}
+
+	if entry != "" {
+		return nil, fmt.Errorf("%w: %s", ErrEntryNotFound, entry)
+	}
+	return nil, ErrNoROMInArchive
+}
+
+func readGzip(data []byte) ([]byte, error) {
+	rc, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("cartridge: reading gzip: %w", err)
+	}
+	defer rc.Close()
+
+	image, err := readAll(rc, "gzip")
+	if err != nil {
+		return nil, err
+	}
+	if len(image) == 0 {
+		return nil, ErrNoROMInArchive
+	}
+	return image, nil
+}
+
+func readAll(r io.Reader, name string) ([]byte, error) {
+	image, err := io.ReadAll(io.LimitReader(r, maxImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("cartridge: reading %s: %w", name, err)
+	}
+	if len(image) > maxImageSize {
+		return nil, ErrImageTooLarge
+	}
+	return image, nil
+}
@@ -0,0 +1,148 @@
+package cartridge
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type archiveEntry struct {
+	name string
+	data []byte
+}
+
+func zipArchive(t *testing.T, entries ...archiveEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		f, err := w.Create(entry.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(entry.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipArchive(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// writeArchive stores data under name in a fresh directory
+func writeArchive(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOpenZip(t *testing.T) {
+	first := inesImage(1, 1, 0x00, 0x01)
+	second := inesImage(2, 1, 0x00, 0x02)
+	archive := zipArchive(t,
+		archiveEntry{"readme.txt", []byte("not a ROM")},
+		archiveEntry{"roms/first.NES", first},
+		archiveEntry{"roms/second.nes", second},
+	)
+	// The extension is not what tells a zip apart
+	path := writeArchive(t, "games.bin", archive)
+
+	tests := []struct {
+		entry   string
+		prgSize int
+	}{
+		{"", 0x4000},
+		{"second.nes", 0x8000},
+		{"roms/second.nes", 0x8000},
+		{"first.NES", 0x4000},
+	}
+	for _, tt := range tests {
+		cart, err := Open(path, Options{Entry: tt.entry})
+		if err != nil {
+			t.Errorf("entry %q: %v", tt.entry, err)
+			continue
+		}
+		if cart.Header.PRGROMSize != tt.prgSize {
+			t.Errorf("entry %q: loaded %d bytes of PRG ROM, want %d", tt.entry, cart.Header.PRGROMSize, tt.prgSize)
+		}
+	}
+
+	if _, err := Open(path, Options{Entry: "missing.nes"}); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("missing entry: got %v, want ErrEntryNotFound", err)
+	}
+	noROM := writeArchive(t, "docs.zip", zipArchive(t, archiveEntry{"readme.txt", []byte("text")}))
+	if _, err := Open(noROM, Options{}); !errors.Is(err, ErrNoROMInArchive) {
+		t.Errorf("zip without ROM: got %v, want ErrNoROMInArchive", err)
+	}
+}
+
+func TestOpenGzip(t *testing.T) {
+	image := inesImage(2, 1, 0x00, 0x03)
+	path := writeArchive(t, "game.nes.gz", gzipArchive(t, image))
+	cart, err := Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cart.Header.PRGROMSize != 0x8000 || cart.CPURead(0x8000, true) != image[16] {
+		t.Errorf("gzip image not loaded: %+v", cart.Header)
+	}
+
+	empty := writeArchive(t, "empty.gz", gzipArchive(t, nil))
+	if _, err := Open(empty, Options{}); !errors.Is(err, ErrNoROMInArchive) {
+		t.Errorf("empty gzip: got %v, want ErrNoROMInArchive", err)
+	}
+}
+
+func TestOpenOversizedArchive(t *testing.T) {
+	huge, err := io.ReadAll(io.LimitReader(zeroReader{}, maxImageSize+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Just at the limit the image is read, and fails as a bad ROM instead
+	atLimit := writeArchive(t, "limit.gz", gzipArchive(t, huge[:maxImageSize]))
+	if _, err := Open(atLimit, Options{}); !errors.Is(err, ErrBadMagic) {
+		t.Errorf("gzip at the limit: got %v, want ErrBadMagic", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"huge.zip", zipArchive(t, archiveEntry{"huge.nes", huge})},
+		{"huge.gz", gzipArchive(t, huge)},
+	}
+	for _, tt := range tests {
+		if _, err := Open(writeArchive(t, tt.name, tt.data), Options{}); !errors.Is(err, ErrImageTooLarge) {
+			t.Errorf("%s: got %v, want ErrImageTooLarge", tt.name, err)
+		}
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -2,98 +2,198 @@ package cartridge
 
 import (
 	"bytes"
+	"crypto/sha1"
 	"encoding/binary"
+	"errors"
 	"fmt"
-	"log"
-	"os"
+	"hash/crc32"
+	"io"
+
+	"github.com/patrickn2/gonesemulator/mapper"
 )
 
-type sHeader struct {
-	Name         [4]byte
-	PrgRomChunks byte
-	ChrRomChunks byte
-	Flags6       byte
-	Flags7       byte
-	PrgRamSize   byte
-	TvSystem1    byte
-	TvSystem2    byte
-	Unused       [5]byte
-}
+var (
+	ErrBadMagic         = errors.New("cartridge: not an iNES file")
+	ErrTruncatedTrainer = errors.New("cartridge: truncated trainer")
+	ErrTruncatedPRG     = errors.New("cartridge: truncated PRG ROM")
+	ErrTruncatedCHR     = errors.New("cartridge: truncated CHR ROM")
+
+	// ErrUnsupportedMapper matches the *mapper.UnsupportedError returned
+	// when no implementation is registered for the header mapper number
+	ErrUnsupportedMapper = mapper.ErrUnsupported
+)
+
+type Cartridge struct {
+	Header Header
+
+	// CRC32 and SHA1 identify the dump, they cover PRG ROM followed by CHR
+	// ROM without header or trainer
+	CRC32 uint32
+	SHA1  [sha1.Size]byte
+
+	// Verified is set when the dump was found in the game database, and
+	// Corrections lists the header fields the database overrode
+	Verified    bool
+	Corrections []Correction
+
+	// Trainer is the 512 byte block some dumps carry ahead of PRG ROM, the
+	// mapper places it in PRG RAM at $7000
+	Trainer []byte
 
-type cartridge struct {
-	header    sHeader
 	prgMemory []byte
 	chrMemory []byte
+	mapper    mapper.Mapper
+	snooper   mapper.Snooper
+	battery   *battery
 }
 
-func New(fileLocation string) *cartridge {
-	file, err := os.Open(fileLocation)
-	if err != nil {
-		log.Fatalln("error opening file", err)
-	}
-	defer file.Close()
-	var header sHeader
+// New loads a ROM from disk with the default options
+func New(fileLocation string) (*Cartridge, error) {
+	return Open(fileLocation, Options{})
+}
+
+// Load reads an iNES or UNIF image and builds the mapper it asks for
+func Load(r io.Reader) (*Cartridge, error) {
+	var raw sHeader
 
 	byteHeader := make([]byte, 0x10)
 
-	_, err = file.Read(byteHeader)
+	_, err := io.ReadFull(r, byteHeader)
 	if err != nil {
-		log.Fatalln("error reading file header", err)
+		return nil, readError(ErrBadMagic, err)
 	}
 
-	err = binary.Read(bytes.NewReader(byteHeader), binary.BigEndian, &header)
+	err = binary.Read(bytes.NewReader(byteHeader), binary.BigEndian, &raw)
 	if err != nil {
-		log.Fatalln("error reading to header struct", err)
+		return nil, err
 	}
 
-	if !bytes.Equal(header.Name[:], []byte{'N', 'E', 'S', 26}) {
-		log.Fatalln("invalid file type", string(header.Name[:]))
+	if bytes.Equal(raw.Name[:], []byte("UNIF")) {
+		return loadUNIF(r)
+	}
+	if !bytes.Equal(raw.Name[:], []byte{'N', 'E', 'S', 26}) {
+		return nil, ErrBadMagic
+	}
+
+	header := parseHeader(raw)
+	if header.PRGROMSize+header.CHRROMSize > maxImageSize {
+		return nil, ErrImageTooLarge
 	}
 
-	mapperID := (header.Flags7>>4)<<4 | header.Flags6>>4
-	prgRom := make([]byte, int(header.PrgRomChunks)*16*1024)
-	chrRom := make([]byte, int(header.ChrRomChunks)*8*1024)
-	nameTableArrangement := header.Flags6 & 0b00000001 // 1 : Horizontal, 0 : Vertical
-	batteryBacked := header.Flags6&0b00000010 > 0
-	trainer := header.Flags6&0b00000100 > 0
-	alternativeNameTable := header.Flags6&0b00001000 > 0
-	vsUnisystem := header.Flags7&0b00000001 > 0
-	playChoice := header.Flags7&0b00000010 > 0
-	flagsIn8_15Nes2 := header.Flags7&0b00001100 == 2
-
-	trainerData := make([]byte, 512)
-
-	if trainer {
-		_, err = file.Read(trainerData)
+	var trainer []byte
+	if header.Trainer {
+		trainer = make([]byte, 512)
+		_, err = io.ReadFull(r, trainer)
 		if err != nil {
-			log.Fatalln("error reading trainer", err)
+			return nil, readError(ErrTruncatedTrainer, err)
 		}
 	}
 
-	_, err = file.Read(prgRom)
+	prgRom := make([]byte, header.PRGROMSize)
+	_, err = io.ReadFull(r, prgRom)
 	if err != nil {
-		log.Fatalln("error reading prg Rom", err)
+		return nil, readError(ErrTruncatedPRG, err)
 	}
-	_, err = file.Read(chrRom)
+	chrRom := make([]byte, header.CHRROMSize)
+	_, err = io.ReadFull(r, chrRom)
 	if err != nil {
-		log.Fatalln("error reading chr Rom", err)
+		return nil, readError(ErrTruncatedCHR, err)
 	}
 
-	fmt.Println("NES Cartridge Information")
-	fmt.Printf("PRG ROM Chunks: %d, size %d\n", header.PrgRomChunks, int(header.PrgRomChunks)*16*1024)
-	fmt.Printf("CHR ROM Chunks: %d, size %d\n", header.ChrRomChunks, int(header.ChrRomChunks)*8*1024)
-	fmt.Printf("Nametable arrangement: %d\n", nameTableArrangement)
-	fmt.Printf("Battery Backed Cartridge: %t\n", batteryBacked)
-	fmt.Printf("Trainer: %t\n", trainer)
-	fmt.Printf("Alternative nametable layout:  %t\n", alternativeNameTable)
-	fmt.Printf("VS Unisystem: %t\n", vsUnisystem)
-	fmt.Printf("PlayChoice-10: %t\n", playChoice)
-	fmt.Printf("NES 2.0 format: %t\n", flagsIn8_15Nes2)
-	fmt.Printf("Mapped Id: %d\n", mapperID)
-
-	return &cartridge{
-		header:    header,
+	return build(header, trainer, prgRom, chrRom)
+}
+
+// build identifies the dump, corrects the header from the game database
+// and instantiates the mapper
+func build(header Header, trainer []byte, prgRom []byte, chrRom []byte) (*Cartridge, error) {
+	cart := &Cartridge{
+		Trainer:   trainer,
 		prgMemory: prgRom,
 		chrMemory: chrRom,
 	}
+
+	crcHash, shaHash := crc32.NewIEEE(), sha1.New()
+	hash := io.MultiWriter(crcHash, shaHash)
+	hash.Write(prgRom)
+	hash.Write(chrRom)
+	cart.CRC32 = crcHash.Sum32()
+	shaHash.Sum(cart.SHA1[:0])
+
+	game, found, err := lookupGame(cart.CRC32, cart.SHA1)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		cart.Verified = true
+		cart.Corrections = game.correct(&header)
+	}
+
+	m, err := mapper.New(header.Mapper, mapper.Config{
+		Submapper:  header.Submapper,
+		PRG:        prgRom,
+		CHR:        chrRom,
+		Trainer:    trainer,
+		PRGRAMSize: header.PRGRAMSize + header.PRGNVRAMSize,
+		CHRRAMSize: header.CHRRAMSize + header.CHRNVRAMSize,
+		Mirroring:  header.Mirroring,
+		Battery:    header.Battery,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cartridge: %w", err)
+	}
+
+	cart.Header = header
+	cart.mapper = m
+	cart.snooper, _ = m.(mapper.Snooper)
+	return cart, nil
+}
+
+// readError reports a short read as the given sentinel error
+func readError(sentinel error, err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return sentinel
+	}
+	return err
+}
+
+func (c *Cartridge) CPURead(addr uint16, bReadOnly bool) byte {
+	data, _ := c.mapper.CPURead(addr, bReadOnly)
+	return data
+}
+
+func (c *Cartridge) CPUWrite(addr uint16, data byte) {
+	c.mapper.CPUWrite(addr, data)
+}
+
+func (c *Cartridge) PPURead(addr uint16) (byte, bool) {
+	return c.mapper.PPURead(addr)
+}
+
+func (c *Cartridge) PPUWrite(addr uint16, data byte) bool {
+	return c.mapper.PPUWrite(addr, data)
+}
+
+func (c *Cartridge) Mirroring() mapper.Mirroring {
+	return c.mapper.Mirroring()
+}
+
+func (c *Cartridge) Reset() {
+	c.mapper.Reset()
+}
+
+func (c *Cartridge) Clock() {
+	c.mapper.Clock()
+	c.autosave()
+}
+
+// SnoopPPU passes CPU writes to the PPU registers on to mappers watching
+// them
+func (c *Cartridge) SnoopPPU(reg uint16, data byte) {
+	if c.snooper != nil {
+		c.snooper.SnoopPPU(reg, data)
+	}
+}
+
+func (c *Cartridge) IRQ() bool {
+	return c.mapper.IRQ()
 }
@@ -0,0 +1,47 @@
+package cartridge
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTrainer(t *testing.T) {
+	trainer := make([]byte, 512)
+	for i := range trainer {
+		trainer[i] = byte(i*7 + 1)
+	}
+
+	// Flags 10 holds the NES 2.0 PRG RAM shift count, 64 << shift bytes
+	tests := []struct {
+		name    string
+		flags7  byte
+		flags10 byte
+	}{
+		{"iNES", 0x00, 0x00},
+		{"NES 2.0 8KB", 0x08, 0x07},
+		{"NES 2.0 no RAM", 0x08, 0x00},
+		{"NES 2.0 128 bytes", 0x08, 0x01},
+		{"NES 2.0 2KB", 0x08, 0x05},
+		{"NES 2.0 32KB", 0x08, 0x09},
+	}
+	for _, tt := range tests {
+		image := []byte{'N', 'E', 'S', 26, 1, 1, 0x04, tt.flags7, 0, 0, tt.flags10, 0, 0, 0, 0, 0}
+		image = append(image, trainer...)
+		image = append(image, make([]byte, 0x4000+0x2000)...)
+
+		cart, err := Load(bytes.NewReader(image))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !bytes.Equal(cart.Trainer, trainer) {
+			t.Errorf("%s: trainer not kept on the cartridge", tt.name)
+		}
+		for i, want := range trainer {
+			addr := uint16(0x7000 + i)
+			if got := cart.CPURead(addr, true); got != want {
+				t.Errorf("%s: $%04X got %02X, want %02X", tt.name, addr, got, want)
+				break
+			}
+		}
+	}
+}
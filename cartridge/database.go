@@ -0,0 +1,195 @@
+package cartridge
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/patrickn2/gonesemulator/mapper"
+)
+
+//go:embed nes20db.xml
+var nes20db []byte
+
+// Correction records a header field the game database overrode
+type Correction struct {
+	Field string
+	From  string
+	To    string
+}
+
+func (c Correction) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Field, c.From, c.To)
+}
+
+type dbSize struct {
+	Size int `xml:"size,attr"`
+}
+
+type dbGame struct {
+	ROM struct {
+		CRC32 string `xml:"crc32,attr"`
+		SHA1  string `xml:"sha1,attr"`
+	} `xml:"rom"`
+	PRGRAM   dbSize `xml:"prgram"`
+	PRGNVRAM dbSize `xml:"prgnvram"`
+	CHRRAM   dbSize `xml:"chrram"`
+	CHRNVRAM dbSize `xml:"chrnvram"`
+	PCB      struct {
+		Mapper    uint16 `xml:"mapper,attr"`
+		Submapper uint8  `xml:"submapper,attr"`
+		Mirroring string `xml:"mirroring,attr"`
+		Battery   int    `xml:"battery,attr"`
+	} `xml:"pcb"`
+	Console struct {
+		Type   uint8 `xml:"type,attr"`
+		Region uint8 `xml:"region,attr"`
+	} `xml:"console"`
+	Expansion struct {
+		Type uint8 `xml:"type,attr"`
+	} `xml:"expansion"`
+}
+
+var (
+	databaseOnce sync.Once
+	databaseMu   sync.RWMutex
+	database     map[uint32][]dbGame
+	databaseErr  error
+)
+
+// LoadDatabase adds the records of a nes20db.xml export to the game
+// database. The embedded file only covers a handful of dumps, loading the
+// full export at startup corrects every game it knows. Records loaded
+// later take precedence.
+func LoadDatabase(r io.Reader) error {
+	databaseOnce.Do(loadDatabase)
+	if databaseErr != nil {
+		return fmt.Errorf("cartridge: reading game database: %w", databaseErr)
+	}
+
+	games, err := parseDatabase(r)
+	if err != nil {
+		return fmt.Errorf("cartridge: reading game database: %w", err)
+	}
+
+	databaseMu.Lock()
+	defer databaseMu.Unlock()
+	for crc, records := range games {
+		database[crc] = append(records, database[crc]...)
+	}
+	return nil
+}
+
+// lookupGame finds the database record for a PRG+CHR image
+func lookupGame(crc uint32, sha1 [20]byte) (dbGame, bool, error) {
+	databaseOnce.Do(loadDatabase)
+	if databaseErr != nil {
+		return dbGame{}, false, fmt.Errorf("cartridge: reading game database: %w", databaseErr)
+	}
+
+	databaseMu.RLock()
+	defer databaseMu.RUnlock()
+	for _, game := range database[crc] {
+		if game.ROM.SHA1 == "" || strings.EqualFold(game.ROM.SHA1, hex.EncodeToString(sha1[:])) {
+			return game, true, nil
+		}
+	}
+	return dbGame{}, false, nil
+}
+
+// Private Methods
+
+func loadDatabase() {
+	database, databaseErr = parseDatabase(bytes.NewReader(nes20db))
+}
+
+// parseDatabase indexes the records of a nes20db.xml file by CRC32, records
+// without a usable CRC32 are skipped
+func parseDatabase(r io.Reader) (map[uint32][]dbGame, error) {
+	var db struct {
+		Games []dbGame `xml:"game"`
+	}
+	if err := xml.NewDecoder(r).Decode(&db); err != nil {
+		return nil, err
+	}
+
+	games := make(map[uint32][]dbGame, len(db.Games))
+	for _, game := range db.Games {
+		var crc uint32
+		if _, err := fmt.Sscanf(game.ROM.CRC32, "%x", &crc); err != nil {
+			continue
+		}
+		games[crc] = append(games[crc], game)
+	}
+	return games, nil
+}
+
+// correct overrides the header with the database record and reports every
+// field that changed. Fields only NES 2.0 headers carry are filled in
+// silently for iNES 1.0 headers.
+func (game dbGame) correct(header *Header) []Correction {
+	var corrections []Correction
+	set := func(field string, from, to any) {
+		if from != to {
+			corrections = append(corrections, Correction{
+				Field: field,
+				From:  fmt.Sprint(from),
+				To:    fmt.Sprint(to),
+			})
+		}
+	}
+
+	set("Mapper", header.Mapper, game.PCB.Mapper)
+	header.Mapper = game.PCB.Mapper
+	if header.NES20 {
+		set("Submapper", header.Submapper, game.PCB.Submapper)
+	}
+	header.Submapper = game.PCB.Submapper
+
+	mirroring := header.Mirroring
+	switch game.PCB.Mirroring {
+	case "H":
+		mirroring = mapper.Horizontal
+	case "V":
+		mirroring = mapper.Vertical
+	case "4":
+		mirroring = mapper.FourScreen
+	}
+	set("Mirroring", header.Mirroring, mirroring)
+	header.Mirroring = mirroring
+
+	battery := game.PCB.Battery != 0
+	set("Battery", header.Battery, battery)
+	header.Battery = battery
+
+	set("PRGRAMSize", header.PRGRAMSize, game.PRGRAM.Size)
+	header.PRGRAMSize = game.PRGRAM.Size
+	set("PRGNVRAMSize", header.PRGNVRAMSize, game.PRGNVRAM.Size)
+	header.PRGNVRAMSize = game.PRGNVRAM.Size
+	set("CHRRAMSize", header.CHRRAMSize, game.CHRRAM.Size)
+	header.CHRRAMSize = game.CHRRAM.Size
+	set("CHRNVRAMSize", header.CHRNVRAMSize, game.CHRNVRAM.Size)
+	header.CHRNVRAMSize = game.CHRNVRAM.Size
+
+	console := ConsoleType(game.Console.Type & 0x03)
+	set("ConsoleType", header.ConsoleType, console)
+	header.ConsoleType = console
+	header.VSUnisystem = console == ConsoleVsSystem
+	header.PlayChoice10 = console == ConsolePlayChoice10
+
+	timing := Timing(game.Console.Region & 0x03)
+	set("Timing", header.Timing, timing)
+	header.Timing = timing
+
+	if header.NES20 {
+		set("ExpansionDevice", header.ExpansionDevice, game.Expansion.Type)
+	}
+	header.ExpansionDevice = game.Expansion.Type
+
+	return corrections
+}
@@ -0,0 +1,138 @@
+package cartridge
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"testing"
+
+	"github.com/patrickn2/gonesemulator/mapper"
+)
+
+// inesImage builds an iNES 1.0 image with PRG and CHR filled with seed
+func inesImage(prgBanks, chrBanks byte, flags6 byte, seed byte) []byte {
+	image := []byte{'N', 'E', 'S', 26, prgBanks, chrBanks, flags6, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	size := int(prgBanks)*0x4000 + int(chrBanks)*0x2000
+	for i := 0; i < size; i++ {
+		image = append(image, byte(i)^seed)
+	}
+	return image
+}
+
+func TestCorrect(t *testing.T) {
+	var game dbGame
+	game.PCB.Mapper = 4
+	game.PCB.Submapper = 1
+	game.PCB.Mirroring = "4"
+	game.PCB.Battery = 1
+	game.PRGNVRAM.Size = 1024
+	game.CHRRAM.Size = 8192
+	game.Console.Region = 1
+	game.Expansion.Type = 1
+
+	header := Header{
+		NES20:      true,
+		Mapper:     1,
+		Mirroring:  mapper.Vertical,
+		PRGRAMSize: 8192,
+		CHRRAMSize: 8192,
+	}
+	corrections := game.correct(&header)
+
+	want := []Correction{
+		{"Mapper", "1", "4"},
+		{"Submapper", "0", "1"},
+		{"Mirroring", "vertical", "four screen"},
+		{"Battery", "false", "true"},
+		{"PRGRAMSize", "8192", "0"},
+		{"PRGNVRAMSize", "0", "1024"},
+		{"Timing", "0", "1"},
+		{"ExpansionDevice", "0", "1"},
+	}
+	if fmt.Sprint(corrections) != fmt.Sprint(want) {
+		t.Errorf("corrections:\n got %v\nwant %v", corrections, want)
+	}
+	if header.Mapper != 4 || header.Submapper != 1 || header.Mirroring != mapper.FourScreen ||
+		!header.Battery || header.PRGRAMSize != 0 || header.PRGNVRAMSize != 1024 ||
+		header.Timing != TimingPAL || header.ExpansionDevice != 1 {
+		t.Errorf("header not corrected: %+v", header)
+	}
+
+	if again := game.correct(&header); len(again) != 0 {
+		t.Errorf("correcting twice reported %v", again)
+	}
+
+	// iNES 1.0 headers have no submapper or expansion device to correct
+	header = Header{Mapper: 4, Mirroring: mapper.FourScreen, Battery: true, PRGNVRAMSize: 1024, CHRRAMSize: 8192, Timing: TimingPAL}
+	if corrections := game.correct(&header); len(corrections) != 0 {
+		t.Errorf("iNES 1.0 header: got corrections %v, want none", corrections)
+	}
+	if header.Submapper != 1 || header.ExpansionDevice != 1 {
+		t.Errorf("iNES 1.0 header not filled in: %+v", header)
+	}
+}
+
+// restoreDatabase puts the game database back the way it was once the
+// test is over
+func restoreDatabase(t *testing.T) {
+	databaseOnce.Do(loadDatabase)
+	databaseMu.RLock()
+	saved := make(map[uint32][]dbGame, len(database))
+	for crc, games := range database {
+		saved[crc] = append([]dbGame(nil), games...)
+	}
+	databaseMu.RUnlock()
+
+	t.Cleanup(func() {
+		databaseMu.Lock()
+		database = saved
+		databaseMu.Unlock()
+	})
+}
+
+func TestLoadDatabase(t *testing.T) {
+	restoreDatabase(t)
+	image := inesImage(2, 0, 0x00, 0x5A)
+	rom := image[16:]
+	digest := sha1.Sum(rom)
+	record := fmt.Sprintf(`<nes20db><game>
+	<rom size="%d" crc32="%08X" sha1="%s"/>
+	<prgram size="8192"/>
+	<chrram size="8192"/>
+	<pcb mapper="2" submapper="0" mirroring="V" battery="0"/>
+	<console type="0" region="0"/>
+	<expansion type="1"/>
+</game></nes20db>`, len(rom), crc32.ChecksumIEEE(rom), strings.ToUpper(hex.EncodeToString(digest[:])))
+
+	cart, err := Load(bytes.NewReader(image))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cart.Verified {
+		t.Fatal("synthetic image verified before its record was loaded")
+	}
+
+	if err := LoadDatabase(strings.NewReader(record)); err != nil {
+		t.Fatal(err)
+	}
+	cart, err = Load(bytes.NewReader(image))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cart.Verified || cart.Header.Mapper != 2 || cart.Header.Mirroring != mapper.Vertical {
+		t.Errorf("header not corrected: verified %v, %+v", cart.Verified, cart.Header)
+	}
+
+	// A record with the same CRC32 but another SHA-1 is not a match
+	digest[0] ^= 0xFF
+	if _, found, _ := lookupGame(crc32.ChecksumIEEE(rom), digest); found {
+		t.Error("record matched a different SHA-1")
+	}
+
+	if err := LoadDatabase(strings.NewReader("<nes20db><game>")); err == nil {
+		t.Error("truncated database loaded without error")
+	}
+}
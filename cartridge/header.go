@@ -0,0 +1,160 @@
+package cartridge
+
+import (
+	"math/bits"
+
+	"github.com/patrickn2/gonesemulator/mapper"
+)
+
+// sHeader is the raw 16 byte header. Bytes 8-15 mean different things in
+// iNES 1.0 and NES 2.0 files.
+type sHeader struct {
+	Name         [4]byte
+	PrgRomChunks byte
+	ChrRomChunks byte
+	Flags6       byte
+	Flags7       byte
+	Flags8       byte
+	Flags9       byte
+	Flags10      byte
+	Flags11      byte
+	Flags12      byte
+	Flags13      byte
+	Flags14      byte
+	Flags15      byte
+}
+
+// Timing is the CPU/PPU timing the game was made for
+type Timing uint8
+
+const (
+	TimingNTSC Timing = iota
+	TimingPAL
+	TimingMultiRegion
+	TimingDendy
+)
+
+// ConsoleType tells which kind of machine the cartridge plugs into
+type ConsoleType uint8
+
+const (
+	ConsoleNES ConsoleType = iota
+	ConsoleVsSystem
+	ConsolePlayChoice10
+	ConsoleExtended
+)
+
+// Header is the decoded iNES or NES 2.0 header. Memory sizes are in bytes.
+type Header struct {
+	// Board and Name come from the MAPR and NAME chunks of UNIF images
+	Board string
+	Name  string
+
+	Mapper       uint16
+	Submapper    uint8
+	PRGROMSize   int
+	CHRROMSize   int
+	PRGRAMSize   int
+	PRGNVRAMSize int
+	CHRRAMSize   int
+	CHRNVRAMSize int
+	Mirroring    mapper.Mirroring
+	Battery      bool
+	Trainer      bool
+	VSUnisystem  bool
+	PlayChoice10 bool
+	NES20        bool
+
+	Timing      Timing
+	ConsoleType ConsoleType
+	// Vs. System PPU and hardware type, only set for ConsoleVsSystem
+	VsPPUType      uint8
+	VsHardwareType uint8
+	// Extended console type, only set for ConsoleExtended
+	ExtendedConsoleType uint8
+	MiscROMs            int
+	ExpansionDevice     uint8
+}
+
+func parseHeader(raw sHeader) Header {
+	header := Header{
+		Mapper:      uint16((raw.Flags7>>4)<<4 | raw.Flags6>>4),
+		PRGROMSize:  int(raw.PrgRomChunks) * 16 * 1024,
+		CHRROMSize:  int(raw.ChrRomChunks) * 8 * 1024,
+		Battery:     raw.Flags6&0b00000010 > 0,
+		Trainer:     raw.Flags6&0b00000100 > 0,
+		ConsoleType: ConsoleType(raw.Flags7 & 0b00000011),
+		NES20:       raw.Flags7&0b00001100 == 0b00001000,
+	}
+	header.VSUnisystem = header.ConsoleType == ConsoleVsSystem
+	header.PlayChoice10 = header.ConsoleType == ConsolePlayChoice10
+
+	// Bit 0 is the nametable arrangement, a horizontal arrangement
+	// being vertical mirroring. Bit 3 asks for four separate nametables.
+	header.Mirroring = mapper.Horizontal
+	if raw.Flags6&0b00000001 > 0 {
+		header.Mirroring = mapper.Vertical
+	}
+	if raw.Flags6&0b00001000 > 0 {
+		header.Mirroring = mapper.FourScreen
+	}
+
+	if !header.NES20 {
+		// iNES 1.0 gives PRG RAM in 8KB units, 0 meaning a single bank,
+		// and boards without CHR ROM have 8KB of CHR RAM
+		header.PRGRAMSize = max(int(raw.Flags8), 1) * 8 * 1024
+		if header.CHRROMSize == 0 {
+			header.CHRRAMSize = 8 * 1024
+		}
+		if raw.Flags9&0b00000001 > 0 {
+			header.Timing = TimingPAL
+		}
+		return header
+	}
+
+	header.Mapper |= uint16(raw.Flags8&0x0F) << 8
+	header.Submapper = raw.Flags8 >> 4
+	header.PRGROMSize = romSize(raw.PrgRomChunks, raw.Flags9&0x0F, 16*1024)
+	header.CHRROMSize = romSize(raw.ChrRomChunks, raw.Flags9>>4, 8*1024)
+	header.PRGRAMSize = shiftSize(raw.Flags10 & 0x0F)
+	header.PRGNVRAMSize = shiftSize(raw.Flags10 >> 4)
+	header.CHRRAMSize = shiftSize(raw.Flags11 & 0x0F)
+	header.CHRNVRAMSize = shiftSize(raw.Flags11 >> 4)
+	header.Timing = Timing(raw.Flags12 & 0b00000011)
+
+	switch header.ConsoleType {
+	case ConsoleVsSystem:
+		header.VsPPUType = raw.Flags13 & 0x0F
+		header.VsHardwareType = raw.Flags13 >> 4
+	case ConsoleExtended:
+		header.ExtendedConsoleType = raw.Flags13 & 0x0F
+	}
+
+	header.MiscROMs = int(raw.Flags14 & 0b00000011)
+	header.ExpansionDevice = raw.Flags15 & 0b00111111
+	return header
+}
+
+// romSize decodes a NES 2.0 ROM size. Normally the MSB nibble extends the
+// LSB byte as a count of units, an MSB of $F switches to the
+// exponent-multiplier form EEEEEEMM meaning 2^E * (MM*2+1) bytes. Exponents
+// too large for any image are clamped just above maxImageSize.
+func romSize(lsb byte, msb byte, unit int) int {
+	if msb != 0x0F {
+		return (int(msb)<<8 | int(lsb)) * unit
+	}
+	exponent := lsb >> 2
+	if int(exponent) >= bits.Len(maxImageSize) {
+		return maxImageSize + 1
+	}
+	multiplier := int(lsb&0b00000011)*2 + 1
+	return (1 << exponent) * multiplier
+}
+
+// shiftSize decodes a NES 2.0 RAM size, 64 << shift bytes or none
+func shiftSize(shift byte) int {
+	if shift == 0 {
+		return 0
+	}
+	return 64 << shift
+}
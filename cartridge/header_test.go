@@ -0,0 +1,38 @@
+package cartridge
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestROMSize(t *testing.T) {
+	tests := []struct {
+		lsb, msb byte
+		unit     int
+		want     int
+	}{
+		{0x02, 0x00, 16 * 1024, 32 * 1024},
+		{0x00, 0x01, 8 * 1024, 256 * 8 * 1024},
+		{0x3C, 0x0F, 16 * 1024, 1 << 15},
+		{0x3D, 0x0F, 16 * 1024, 3 << 15},
+		{0x6B, 0x0F, 16 * 1024, 7 << 26},
+		{0x6C, 0x0F, 16 * 1024, maxImageSize + 1},
+		{0xFC, 0x0F, 16 * 1024, maxImageSize + 1},
+	}
+	for _, tt := range tests {
+		if got := romSize(tt.lsb, tt.msb, tt.unit); got != tt.want {
+			t.Errorf("romSize(%02X, %X): got %d, want %d", tt.lsb, tt.msb, got, tt.want)
+		}
+	}
+}
+
+func TestLoadRejectsHugeROM(t *testing.T) {
+	for _, prg := range []byte{0xFC, 0xA0, 0x6B} {
+		image := []byte{'N', 'E', 'S', 26, prg, 0x00, 0x00, 0x08, 0x00, 0x0F, 0, 0, 0, 0, 0, 0}
+		_, err := Load(bytes.NewReader(image))
+		if !errors.Is(err, ErrImageTooLarge) {
+			t.Errorf("PRG size byte %02X: got %v, want ErrImageTooLarge", prg, err)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package cartridge
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrUnknownPatch        = errors.New("cartridge: unknown patch format")
+	ErrCorruptPatch        = errors.New("cartridge: corrupt patch")
+	ErrPatchChecksum       = errors.New("cartridge: patch checksum mismatch")
+	ErrPatchSourceMismatch = errors.New("cartridge: patch is for a different ROM")
+	ErrPatchTargetMismatch = errors.New("cartridge: patched ROM checksum mismatch")
+)
+
+// maxPatchNumber bounds the numbers in UPS and BPS patches, large enough
+// for a BPS action over a whole image
+const maxPatchNumber = maxImageSize<<2 | 0x03
+
+// patchExtensions are looked up next to the ROM when auto patching
+var patchExtensions = []string{".ips", ".ups", ".bps"}
+
+// ApplyPatch applies an IPS, UPS or BPS patch to a whole ROM image,
+// header included, and returns the patched copy
+func ApplyPatch(rom []byte, patch []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(patch, []byte("PATCH")):
+		return applyIPS(rom, patch)
+	case bytes.HasPrefix(patch, []byte("UPS1")):
+		return applyUPS(rom, patch)
+	case bytes.HasPrefix(patch, []byte("BPS1")):
+		return applyBPS(rom, patch)
+	}
+	return nil, ErrUnknownPatch
+}
+
+// Private Methods
+
+// patchFiles lists the patches to apply, in order. Explicit patches come
+// first, siblings sharing the ROM basename are added when auto patching.
+func patchFiles(fileLocation string, opts Options) []string {
+	files := append([]string(nil), opts.Patches...)
+	if !opts.AutoPatch {
+		return files
+	}
+
+	base := strings.TrimSuffix(fileLocation, filepath.Ext(fileLocation))
+	for _, ext := range patchExtensions {
+		candidate := base + ext
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			files = append(files, candidate)
+		}
+	}
+	return files
+}
+
+func applyPatches(rom []byte, files []string) ([]byte, error) {
+	for _, file := range files {
+		patch, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		rom, err = ApplyPatch(rom, patch)
+		if err != nil {
+			return nil, fmt.Errorf("%w (%s)", err, filepath.Base(file))
+		}
+	}
+	return rom, nil
+}
+
+// applyIPS handles the IPS format: 24 bit offset and 16 bit length records
+// with run length records for zero lengths, up to "EOF" and an optional
+// truncation size
+func applyIPS(rom []byte, patch []byte) ([]byte, error) {
+	out := append([]byte(nil), rom...)
+	pos := 5
+
+	for {
+		if pos+3 > len(patch) {
+			return nil, ErrCorruptPatch
+		}
+		if string(patch[pos:pos+3]) == "EOF" {
+			pos += 3
+			break
+		}
+		if pos+5 > len(patch) {
+			return nil, ErrCorruptPatch
+		}
+		offset := int(patch[pos])<<16 | int(patch[pos+1])<<8 | int(patch[pos+2])
+		size := int(binary.BigEndian.Uint16(patch[pos+3:]))
+		pos += 5
+
+		var data []byte
+		if size == 0 {
+			if pos+3 > len(patch) {
+				return nil, ErrCorruptPatch
+			}
+			size = int(binary.BigEndian.Uint16(patch[pos:]))
+			data = bytes.Repeat(patch[pos+2:pos+3], size)
+			pos += 3
+		} else {
+			if pos+size > len(patch) {
+				return nil, ErrCorruptPatch
+			}
+			data = patch[pos : pos+size]
+			pos += size
+		}
+
+		if offset+size > len(out) {
+			out = append(out, make([]byte, offset+size-len(out))...)
+		}
+		copy(out[offset:], data)
+	}
+
+	if pos+3 <= len(patch) {
+		size := int(patch[pos])<<16 | int(patch[pos+1])<<8 | int(patch[pos+2])
+		if size < len(out) {
+			out = out[:size]
+		}
+	}
+	return out, nil
+}
+
+// applyUPS handles the UPS format: runs of bytes XORed into the source at
+// variable length skips, followed by source, target and patch CRC32s
+func applyUPS(rom []byte, patch []byte) ([]byte, error) {
+	body, sourceCRC, targetCRC, err := patchFooter(patch)
+	if err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(rom) != sourceCRC {
+		return nil, ErrPatchSourceMismatch
+	}
+
+	p := &patchReader{data: body, pos: 4}
+	sourceSize := p.number()
+	targetSize := p.number()
+	if p.err != nil || sourceSize != uint64(len(rom)) || targetSize > maxImageSize {
+		return nil, ErrCorruptPatch
+	}
+
+	out := make([]byte, targetSize)
+	copy(out, rom)
+	offset := uint64(0)
+	for p.pos < len(p.data) {
+		offset += p.number()
+		for {
+			x := p.byte()
+			if p.err != nil {
+				return nil, ErrCorruptPatch
+			}
+			if x == 0 {
+				offset++
+				break
+			}
+			if offset < targetSize {
+				out[offset] ^= x
+			}
+			offset++
+		}
+	}
+	if p.err != nil {
+		return nil, ErrCorruptPatch
+	}
+
+	if crc32.ChecksumIEEE(out) != targetCRC {
+		return nil, ErrPatchTargetMismatch
+	}
+	return out, nil
+}
+
+// applyBPS handles the BPS format: a stream of source read, target read,
+// source copy and target copy actions, followed by the same CRC32s as UPS
+func applyBPS(rom []byte, patch []byte) ([]byte, error) {
+	body, sourceCRC, targetCRC, err := patchFooter(patch)
+	if err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(rom) != sourceCRC {
+		return nil, ErrPatchSourceMismatch
+	}
+
+	p := &patchReader{data: body, pos: 4}
+	sourceSize := p.number()
+	targetSize := p.number()
+	metadataSize := p.number()
+	if p.err != nil || sourceSize != uint64(len(rom)) || targetSize > maxImageSize || metadataSize > uint64(len(body)) {
+		return nil, ErrCorruptPatch
+	}
+	p.pos += int(metadataSize)
+
+	out := make([]byte, 0, targetSize)
+	var sourceOffset, targetOffset int
+	for p.pos < len(p.data) && p.err == nil {
+		action := p.number()
+		length := int(action>>2) + 1
+		if len(out)+length > int(targetSize) {
+			return nil, ErrCorruptPatch
+		}
+
+		switch action & 0x03 {
+		case 0: // source read
+			if len(out)+length > len(rom) {
+				return nil, ErrCorruptPatch
+			}
+			out = append(out, rom[len(out):len(out)+length]...)
+		case 1: // target read
+			if p.pos+length > len(p.data) {
+				return nil, ErrCorruptPatch
+			}
+			out = append(out, p.data[p.pos:p.pos+length]...)
+			p.pos += length
+		case 2: // source copy
+			sourceOffset += p.signed()
+			if sourceOffset < 0 || sourceOffset > len(rom)-length {
+				return nil, ErrCorruptPatch
+			}
+			out = append(out, rom[sourceOffset:sourceOffset+length]...)
+			sourceOffset += length
+		case 3: // target copy, byte by byte since the ranges may overlap
+			targetOffset += p.signed()
+			if targetOffset < 0 || targetOffset >= len(out) {
+				return nil, ErrCorruptPatch
+			}
+			for i := 0; i < length; i++ {
+				out = append(out, out[targetOffset])
+				targetOffset++
+			}
+		}
+	}
+	if p.err != nil || len(out) != int(targetSize) {
+		return nil, ErrCorruptPatch
+	}
+
+	if crc32.ChecksumIEEE(out) != targetCRC {
+		return nil, ErrPatchTargetMismatch
+	}
+	return out, nil
+}
+
+// patchFooter checks the CRC32 UPS and BPS patches carry of themselves and
+// splits off the source and target CRC32s
+func patchFooter(patch []byte) (body []byte, sourceCRC uint32, targetCRC uint32, err error) {
+	if len(patch) < 4+12 {
+		return nil, 0, 0, ErrCorruptPatch
+	}
+	footer := patch[len(patch)-12:]
+	if crc32.ChecksumIEEE(patch[:len(patch)-4]) != binary.LittleEndian.Uint32(footer[8:]) {
+		return nil, 0, 0, ErrPatchChecksum
+	}
+	return patch[:len(patch)-12], binary.LittleEndian.Uint32(footer), binary.LittleEndian.Uint32(footer[4:]), nil
+}
+
+// patchReader decodes the variable length numbers shared by UPS and BPS
+type patchReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (p *patchReader) byte() byte {
+	if p.pos >= len(p.data) {
+		p.err = ErrCorruptPatch
+		return 0x00
+	}
+	b := p.data[p.pos]
+	p.pos++
+	return b
+}
+
+// number reads 7 bits per byte, least significant first, with the high
+// bit ending the number. Each continuation also adds one so that every
+// value has a single encoding. Numbers above maxPatchNumber are corrupt.
+func (p *patchReader) number() uint64 {
+	var value uint64
+	shift := uint64(1)
+	for p.err == nil {
+		x := p.byte()
+		value += uint64(x&0x7F) * shift
+		if x&0x80 != 0 {
+			break
+		}
+		shift <<= 7
+		value += shift
+		if value > maxPatchNumber {
+			break
+		}
+	}
+	if value > maxPatchNumber {
+		p.err = ErrCorruptPatch
+		return 0
+	}
+	return value
+}
+
+// signed reads a BPS relative offset, sign in the lowest bit
+func (p *patchReader) signed() int {
+	data := p.number()
+	if data&0x01 != 0 {
+		return -int(data >> 1)
+	}
+	return int(data >> 1)
+}
@@ -0,0 +1,107 @@
+package cartridge
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"testing"
+)
+
+// encodeNumber writes a UPS/BPS variable length number
+func encodeNumber(value uint64) []byte {
+	var out []byte
+	for {
+		x := byte(value & 0x7F)
+		value >>= 7
+		if value == 0 {
+			return append(out, 0x80|x)
+		}
+		out = append(out, x)
+		value--
+	}
+}
+
+// bpsPatch wraps BPS actions with the header and a valid footer
+func bpsPatch(source, target []byte, actions ...[]byte) []byte {
+	patch := []byte("BPS1")
+	patch = append(patch, encodeNumber(uint64(len(source)))...)
+	patch = append(patch, encodeNumber(uint64(len(target)))...)
+	patch = append(patch, encodeNumber(0)...)
+	for _, action := range actions {
+		patch = append(patch, action...)
+	}
+	patch = binary.LittleEndian.AppendUint32(patch, crc32.ChecksumIEEE(source))
+	patch = binary.LittleEndian.AppendUint32(patch, crc32.ChecksumIEEE(target))
+	return binary.LittleEndian.AppendUint32(patch, crc32.ChecksumIEEE(patch))
+}
+
+// sourceCopy encodes a BPS source copy of length bytes at a relative offset
+func sourceCopy(length int, relative uint64) []byte {
+	action := encodeNumber(uint64(length-1)<<2 | 2)
+	return append(action, encodeNumber(relative)...)
+}
+
+func TestApplyIPS(t *testing.T) {
+	rom := []byte{0, 1, 2, 3, 4, 5, 6, 7}
+	patch := []byte("PATCH")
+	patch = append(patch, 0x00, 0x00, 0x02, 0x00, 0x02, 0xAA, 0xBB)
+	patch = append(patch, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x04, 0xCC)
+	patch = append(patch, "EOF"...)
+	want := []byte{0, 1, 0xAA, 0xBB, 4, 5, 0xCC, 0xCC, 0xCC, 0xCC}
+
+	got, err := ApplyPatch(rom, patch)
+	if err != nil || !bytes.Equal(got, want) {
+		t.Errorf("got %X, %v, want %X", got, err, want)
+	}
+}
+
+func TestApplyBPS(t *testing.T) {
+	source := []byte("ABCDEFGH")
+	target := []byte("EFGHABCDxyxyx")
+	patch := bpsPatch(source, target,
+		sourceCopy(4, 4<<1),
+		sourceCopy(4, 8<<1|1),
+		append(encodeNumber(1<<2|1), 'x', 'y'),
+		append(encodeNumber(2<<2|3), encodeNumber(8<<1)...),
+	)
+
+	got, err := ApplyPatch(source, patch)
+	if err != nil || !bytes.Equal(got, target) {
+		t.Errorf("got %q, %v, want %q", got, err, target)
+	}
+}
+
+func TestApplyBPSRejectsHugeOffsets(t *testing.T) {
+	source := []byte("ABCDEFGH")
+	target := []byte("ABCDEFGH")
+	tests := []uint64{
+		math.MaxInt64,
+		math.MaxUint64 - 1,
+		maxPatchNumber,
+		uint64(len(source)) << 1,
+	}
+	for _, relative := range tests {
+		patch := bpsPatch(source, target, sourceCopy(8, relative))
+		if _, err := ApplyPatch(source, patch); !errors.Is(err, ErrCorruptPatch) {
+			t.Errorf("relative offset %d: got %v, want ErrCorruptPatch", relative, err)
+		}
+	}
+}
+
+func TestApplyPatchChecksums(t *testing.T) {
+	source := []byte("ABCDEFGH")
+	patch := bpsPatch(source, source, sourceCopy(8, 0))
+
+	if _, err := ApplyPatch([]byte("abcdefgh"), patch); !errors.Is(err, ErrPatchSourceMismatch) {
+		t.Errorf("other source: got %v, want ErrPatchSourceMismatch", err)
+	}
+	patch[5] ^= 0x01
+	if _, err := ApplyPatch(source, patch); !errors.Is(err, ErrPatchChecksum) {
+		t.Errorf("damaged patch: got %v, want ErrPatchChecksum", err)
+	}
+	if _, err := ApplyPatch(source, []byte("NOPE")); !errors.Is(err, ErrUnknownPatch) {
+		t.Errorf("unknown patch: got %v, want ErrUnknownPatch", err)
+	}
+}
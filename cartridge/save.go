@@ -0,0 +1,164 @@
+package cartridge
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultAutosaveInterval is used when Options.AutosaveInterval is zero
+const DefaultAutosaveInterval = 30 * time.Second
+
+// autosaveCheckCycles is how many CPU cycles pass between looks at the
+// wall clock, about 40ms of emulated time
+const autosaveCheckCycles = 1 << 16
+
+// battery tracks the .sav file behind battery backed memory
+type battery struct {
+	path     string
+	interval time.Duration
+
+	saved    []byte
+	lastSave time.Time
+	cycles   uint64
+
+	// err is the first autosave that failed, reported by Close
+	err error
+}
+
+// SavePath is the .sav file backing the battery RAM, empty when the
+// cartridge has none or was not opened from disk
+func (c *Cartridge) SavePath() string {
+	if c.battery == nil {
+		return ""
+	}
+	return c.battery.path
+}
+
+// Save writes the battery RAM to its .sav file if it changed since the
+// last save. The file is replaced atomically.
+func (c *Cartridge) Save() error {
+	if c.battery == nil {
+		return nil
+	}
+	ram := c.mapper.BatteryRAM()
+	c.battery.lastSave = time.Now()
+	if bytes.Equal(ram, c.battery.saved) {
+		return nil
+	}
+	if err := writeFileAtomic(c.battery.path, ram); err != nil {
+		return err
+	}
+	c.battery.saved = append(c.battery.saved[:0], ram...)
+	return nil
+}
+
+// AutosaveErr returns the first periodic save that failed, nil when every
+// autosave so far succeeded
+func (c *Cartridge) AutosaveErr() error {
+	if c.battery == nil {
+		return nil
+	}
+	return c.battery.err
+}
+
+// Close flushes the battery RAM, call it when the emulator shuts down. It
+// also reports a failed autosave, even when the final save succeeded.
+func (c *Cartridge) Close() error {
+	if c.battery == nil {
+		return nil
+	}
+	return errors.Join(c.battery.err, c.Save())
+}
+
+// Private Methods
+
+// attachSave restores the battery RAM from its .sav file and remembers
+// where to write it back
+func (c *Cartridge) attachSave(fileLocation string, opts Options) error {
+	ram := c.mapper.BatteryRAM()
+	if len(ram) == 0 {
+		return nil
+	}
+
+	dir := opts.SaveDir
+	if dir == "" {
+		dir = filepath.Dir(fileLocation)
+	}
+	base := filepath.Base(fileLocation)
+	base = strings.TrimSuffix(base, filepath.Ext(base)) + ".sav"
+
+	interval := opts.AutosaveInterval
+	if interval == 0 {
+		interval = DefaultAutosaveInterval
+	}
+
+	c.battery = &battery{
+		path:     filepath.Join(dir, base),
+		interval: interval,
+		lastSave: time.Now(),
+	}
+
+	data, err := os.ReadFile(c.battery.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	copy(ram, data)
+	c.battery.saved = append([]byte(nil), ram...)
+	return nil
+}
+
+// autosave is called every CPU cycle and saves once the interval passed.
+// A failed write is retried at the next interval, the first failure is
+// kept for AutosaveErr and Close.
+func (c *Cartridge) autosave() {
+	if c.battery == nil || c.battery.interval < 0 {
+		return
+	}
+	c.battery.cycles++
+	if c.battery.cycles%autosaveCheckCycles != 0 {
+		return
+	}
+	if time.Since(c.battery.lastSave) >= c.battery.interval {
+		if err := c.Save(); err != nil && c.battery.err == nil {
+			c.battery.err = fmt.Errorf("cartridge: autosave: %w", err)
+		}
+	}
+}
+
+// writeFileAtomic writes to a temporary file in the same directory and
+// renames it over the destination, so a crash leaves the old or the new
+// file but never a partial one
+func writeFileAtomic(name string, data []byte) error {
+	dir := filepath.Dir(name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,144 @@
+package cartridge
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeROM stores a battery backed NROM image in dir
+func writeROM(t *testing.T, dir string) string {
+	t.Helper()
+	path := filepath.Join(dir, "game.nes")
+	if err := os.WriteFile(path, inesImage(1, 1, 0x02, 0x33), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// tempFiles lists the leftovers of writeFileAtomic in dir
+func tempFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "saves", "game.sav")
+
+	for _, data := range [][]byte{[]byte("first"), []byte("second write")} {
+		if err := writeFileAtomic(name, data); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(name)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("read back %q, %v, want %q", got, err, data)
+		}
+	}
+	if leftovers := tempFiles(t, filepath.Dir(name)); len(leftovers) != 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+
+	// A destination that cannot be replaced fails without leftovers
+	blocked := filepath.Join(dir, "blocked.sav")
+	if err := os.MkdirAll(filepath.Join(blocked, "child"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(blocked, []byte("data")); err == nil {
+		t.Error("replacing a non-empty directory succeeded")
+	}
+	if leftovers := tempFiles(t, dir); len(leftovers) != 0 {
+		t.Errorf("temporary files left behind after a failure: %v", leftovers)
+	}
+}
+
+func TestAutosave(t *testing.T) {
+	dir := t.TempDir()
+	rom := writeROM(t, dir)
+	cart, err := Open(rom, Options{AutosaveInterval: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "game.sav"); cart.SavePath() != want {
+		t.Fatalf("SavePath %q, want %q", cart.SavePath(), want)
+	}
+
+	cart.CPUWrite(0x6000, 0xA5)
+	for i := 0; i < autosaveCheckCycles-1; i++ {
+		cart.Clock()
+	}
+	if _, err := os.Stat(cart.SavePath()); !os.IsNotExist(err) {
+		t.Fatalf("saved before the wall clock was checked: %v", err)
+	}
+	cart.Clock()
+	data, err := os.ReadFile(cart.SavePath())
+	if err != nil || len(data) != 0x2000 || data[0] != 0xA5 {
+		t.Fatalf("autosave wrote %d bytes, %v", len(data), err)
+	}
+	if err := cart.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The save is loaded back into PRG RAM
+	cart, err = Open(rom, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cart.CPURead(0x6000, true); got != 0xA5 {
+		t.Errorf("reloaded $6000 = %02X, want A5", got)
+	}
+}
+
+func TestAutosaveOnlyOnClose(t *testing.T) {
+	dir := t.TempDir()
+	saves := filepath.Join(dir, "saves")
+	cart, err := Open(writeROM(t, dir), Options{SaveDir: saves, AutosaveInterval: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cart.CPUWrite(0x6000, 0x5A)
+	for i := 0; i < 2*autosaveCheckCycles; i++ {
+		cart.Clock()
+	}
+	if _, err := os.Stat(cart.SavePath()); !os.IsNotExist(err) {
+		t.Fatalf("saved with autosave disabled: %v", err)
+	}
+	if err := cart.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(saves, "game.sav"))
+	if err != nil || data[0] != 0x5A {
+		t.Errorf("Close did not save: %v", err)
+	}
+}
+
+func TestAutosaveError(t *testing.T) {
+	dir := t.TempDir()
+	saves := filepath.Join(dir, "saves")
+	cart, err := Open(writeROM(t, dir), Options{SaveDir: saves, AutosaveInterval: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A file where the save directory should be makes every save fail
+	if err := os.WriteFile(saves, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cart.CPUWrite(0x6000, 0x01)
+	for i := 0; i < autosaveCheckCycles; i++ {
+		cart.Clock()
+	}
+	if cart.AutosaveErr() == nil {
+		t.Error("failed autosave not reported")
+	}
+	if err := cart.Close(); err == nil {
+		t.Error("Close did not report the failed saves")
+	}
+}
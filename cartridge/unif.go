@@ -0,0 +1,216 @@
+package cartridge
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/patrickn2/gonesemulator/mapper"
+)
+
+var (
+	ErrTruncatedChunk = errors.New("cartridge: truncated UNIF chunk")
+	ErrMissingBoard   = errors.New("cartridge: UNIF image without MAPR chunk")
+	ErrUnknownBoard   = errors.New("cartridge: unknown UNIF board")
+)
+
+// unifBoard is the iNES mapper implementing a UNIF board
+type unifBoard struct {
+	mapper    uint16
+	submapper uint8
+}
+
+// unifBoards maps board names, without their NES-, HVC-, UNL- style
+// prefix, onto mapper numbers
+var unifBoards = map[string]unifBoard{
+	"NROM":     {0, 0},
+	"NROM-128": {0, 0},
+	"NROM-256": {0, 0},
+	"RROM":     {0, 0},
+	"SAROM":    {1, 0},
+	"SBROM":    {1, 0},
+	"SCROM":    {1, 0},
+	"SEROM":    {1, 5},
+	"SGROM":    {1, 0},
+	"SKROM":    {1, 0},
+	"SLROM":    {1, 0},
+	"SL1ROM":   {1, 0},
+	"SNROM":    {1, 0},
+	"SOROM":    {1, 0},
+	"SUROM":    {1, 0},
+	"SXROM":    {1, 0},
+	"TBROM":    {4, 0},
+	"TEROM":    {4, 0},
+	"TFROM":    {4, 0},
+	"TGROM":    {4, 0},
+	"TKROM":    {4, 0},
+	"TLROM":    {4, 0},
+	"TR1ROM":   {4, 0},
+	"TSROM":    {4, 0},
+	"TVROM":    {4, 0},
+	"HKROM":    {4, 1},
+	"UNROM":    {2, 0},
+	"UOROM":    {2, 0},
+	"UN1ROM":   {94, 0},
+	"CNROM":    {3, 0},
+	"AMROM":    {7, 0},
+	"ANROM":    {7, 0},
+	"AOROM":    {7, 0},
+	"GNROM":    {66, 0},
+	"MHROM":    {66, 0},
+	"PNROM":    {9, 0},
+	"PEEOROM":  {9, 0},
+	"FJROM":    {10, 0},
+	"FKROM":    {10, 0},
+	"EKROM":    {5, 0},
+	"ELROM":    {5, 0},
+	"ETROM":    {5, 0},
+	"EWROM":    {5, 0},
+	"JLROM":    {69, 0},
+	"JSROM":    {69, 0},
+	"BNROM":    {34, 2},
+	"NINA-001": {34, 1},
+
+	"COLORDREAMS-74*377": {11, 0},
+}
+
+// unifPrefixes are stripped from MAPR before looking the board up
+var unifPrefixes = []string{"NES-", "HVC-", "UNL-", "BTL-", "BMC-", "AVE-", "IREM-", "KONAMI-"}
+
+// loadUNIF reads the chunks following the UNIF magic, which Load already
+// consumed along with the first 12 bytes of the 32 byte header
+func loadUNIF(r io.Reader) (*Cartridge, error) {
+	if _, err := io.CopyN(io.Discard, r, 16); err != nil {
+		return nil, readError(ErrBadMagic, err)
+	}
+
+	var (
+		header  = Header{PRGRAMSize: 8 * 1024}
+		prg     [16][]byte
+		chr     [16][]byte
+		chunkID [4]byte
+		length  uint32
+	)
+	header.Mirroring = mapper.Horizontal
+
+	for {
+		err := binary.Read(r, binary.LittleEndian, &chunkID)
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			err = binary.Read(r, binary.LittleEndian, &length)
+		}
+		if err != nil {
+			return nil, readError(ErrTruncatedChunk, err)
+		}
+		// No chunk can be larger than a whole image, so a length above
+		// that is a damaged file that would run out of data anyway
+		if length > maxImageSize {
+			return nil, ErrTruncatedChunk
+		}
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, readError(ErrTruncatedChunk, err)
+		}
+
+		id := string(chunkID[:])
+		switch {
+		case id == "MAPR":
+			header.Board = unifString(data)
+		case id == "NAME":
+			header.Name = unifString(data)
+		case strings.HasPrefix(id, "PRG"), strings.HasPrefix(id, "CHR"):
+			var index int
+			if _, err := fmt.Sscanf(id[3:], "%X", &index); err != nil {
+				continue
+			}
+			if id[:3] == "PRG" {
+				prg[index] = data
+			} else {
+				chr[index] = data
+			}
+		case id == "MIRR" && len(data) > 0:
+			switch data[0] {
+			case 1:
+				header.Mirroring = mapper.Vertical
+			case 2:
+				header.Mirroring = mapper.SingleScreenLow
+			case 3:
+				header.Mirroring = mapper.SingleScreenHigh
+			case 4:
+				header.Mirroring = mapper.FourScreen
+			}
+		case id == "BATR":
+			header.Battery = true
+		case id == "TVCI" && len(data) > 0:
+			switch data[0] {
+			case 1:
+				header.Timing = TimingPAL
+			case 2:
+				header.Timing = TimingMultiRegion
+			}
+		case id == "CTRL" && len(data) > 0:
+			header.ExpansionDevice = unifExpansion(data[0])
+		}
+	}
+
+	if header.Board == "" {
+		return nil, ErrMissingBoard
+	}
+	board, ok := lookupBoard(header.Board)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownBoard, header.Board)
+	}
+	header.Mapper = board.mapper
+	header.Submapper = board.submapper
+
+	prgRom := bytes.Join(prg[:], nil)
+	chrRom := bytes.Join(chr[:], nil)
+	header.PRGROMSize = len(prgRom)
+	header.CHRROMSize = len(chrRom)
+	if len(chrRom) == 0 {
+		header.CHRRAMSize = 8 * 1024
+	}
+
+	return build(header, nil, prgRom, chrRom)
+}
+
+func lookupBoard(name string) (unifBoard, bool) {
+	name = strings.ToUpper(name)
+	for _, prefix := range unifPrefixes {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	board, ok := unifBoards[name]
+	return board, ok
+}
+
+// unifString reads a NUL terminated chunk
+func unifString(data []byte) string {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		data = data[:i]
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// unifExpansion turns the CTRL controller bits into the NES 2.0 expansion
+// device, the most specific device winning
+func unifExpansion(ctrl byte) uint8 {
+	switch {
+	case ctrl&0x20 != 0: // Four Score
+		return 0x02
+	case ctrl&0x02 != 0: // Zapper
+		return 0x08
+	case ctrl&0x08 != 0: // Arkanoid controller
+		return 0x0F
+	case ctrl&0x10 != 0: // Power Pad
+		return 0x0B
+	case ctrl&0x01 != 0: // standard controllers
+		return 0x01
+	}
+	return 0x00
+}
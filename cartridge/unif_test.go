@@ -0,0 +1,69 @@
+package cartridge
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/patrickn2/gonesemulator/mapper"
+)
+
+// unifImage builds a UNIF image from chunk IDs and their data
+func unifImage(chunks ...any) []byte {
+	image := append([]byte("UNIF"), make([]byte, 28)...)
+	for i := 0; i < len(chunks); i += 2 {
+		data := chunks[i+1].([]byte)
+		image = append(image, chunks[i].(string)...)
+		image = binary.LittleEndian.AppendUint32(image, uint32(len(data)))
+		image = append(image, data...)
+	}
+	return image
+}
+
+func TestLoadUNIF(t *testing.T) {
+	prg := bytes.Repeat([]byte{0xEA}, 0x8000)
+	chr := bytes.Repeat([]byte{0x55}, 0x2000)
+	image := unifImage(
+		"MAPR", []byte("NES-CNROM\x00"),
+		"NAME", []byte("Test\x00"),
+		"PRG0", prg,
+		"CHR0", chr,
+		"MIRR", []byte{1},
+	)
+
+	cart, err := Load(bytes.NewReader(image))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := cart.Header
+	if h.Board != "NES-CNROM" || h.Name != "Test" || h.Mapper != 3 || h.Mirroring != mapper.Vertical ||
+		h.PRGROMSize != len(prg) || h.CHRROMSize != len(chr) {
+		t.Errorf("unexpected header %+v", h)
+	}
+	if got := cart.CPURead(0xFFFF, true); got != 0xEA {
+		t.Errorf("PRG read: got %02X, want EA", got)
+	}
+}
+
+func TestLoadUNIFErrors(t *testing.T) {
+	huge := unifImage("MAPR", []byte("NES-NROM-256\x00"))
+	huge = append(huge, "PRG0"...)
+	huge = binary.LittleEndian.AppendUint32(huge, 0xFFFFFFF0)
+
+	tests := []struct {
+		name  string
+		image []byte
+		want  error
+	}{
+		{"huge chunk", huge, ErrTruncatedChunk},
+		{"short chunk", unifImage("PRG0", []byte{1, 2, 3})[:40], ErrTruncatedChunk},
+		{"no board", unifImage("PRG0", make([]byte, 0x8000)), ErrMissingBoard},
+		{"unknown board", unifImage("MAPR", []byte("NES-NOPE\x00")), ErrUnknownBoard},
+	}
+	for _, tt := range tests {
+		if _, err := Load(bytes.NewReader(tt.image)); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
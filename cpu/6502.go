@@ -39,6 +39,9 @@ type cpu struct {
 	opcode         byte
 	cycles         byte
 	flags          flags
+	nmiLine        bool
+	nmiPending     bool
+	irqLine        uint8
 }
 
 func New(bus *bus.BUS) *cpu {
@@ -66,6 +69,8 @@ func New(bus *bus.BUS) *cpu {
 			n: 1 << 7, // Negative
 		},
 	}
+	// Power up state, Reset then moves the stack pointer down to 0xFD
+	c.status = c.flags.u | c.flags.i
 	c.lookup = [256]instruction{
 		{"BRK", c.brk, c.imm, 7},
 		{"ORA", c.ora, c.izx, 6},
@@ -331,18 +336,78 @@ func New(bus *bus.BUS) *cpu {
 
 func (c *cpu) Clock() {
 	if c.cycles == 0 {
-		c.opcode = c.read(c.programCounter)
-		c.programCounter++
-		c.cycles = c.lookup[c.opcode].cycles
-		addrMode := c.lookup[c.opcode].addrMode()
-		operate := c.lookup[c.opcode].operate()
-		if addrMode && operate {
-			c.cycles++
+		switch {
+		case c.nmiPending:
+			c.nmiPending = false
+			c.NMI()
+		case c.irqLine != 0 && c.getFlag(c.flags.i) == 0:
+			c.IRQ()
+		default:
+			c.opcode = c.read(c.programCounter)
+			c.programCounter++
+			c.cycles = c.lookup[c.opcode].cycles
+			addrMode := c.lookup[c.opcode].addrMode()
+			operate := c.lookup[c.opcode].operate()
+			if addrMode && operate {
+				c.cycles++
+			}
 		}
 	}
 	c.cycles--
 }
 
+// Reset jumps to the address at $FFFC. Like the /RST line it only sets the
+// interrupt disable flag, the other registers keep their values.
+func (c *cpu) Reset() {
+	// Reset runs the interrupt sequence with the writes suppressed,
+	// so the stack pointer still moves down three bytes
+	c.stackPointer -= 3
+	c.setFlag(c.flags.i, true)
+
+	c.addrAbs = 0xFFFC
+	lo := c.read(c.addrAbs + 0)
+	hi := c.read(c.addrAbs + 1)
+	c.programCounter = uint16(hi)<<8 | uint16(lo)
+
+	c.addrRel = 0x0000
+	c.addrAbs = 0x0000
+	c.fetched = 0x00
+	c.nmiPending = false
+
+	c.cycles = 7
+}
+
+// IRQ services a maskable interrupt through the vector at $FFFE
+func (c *cpu) IRQ() {
+	if c.getFlag(c.flags.i) == 0 {
+		c.interrupt(0xFFFE)
+	}
+}
+
+// NMI services a non maskable interrupt through the vector at $FFFA
+func (c *cpu) NMI() {
+	c.interrupt(0xFFFA)
+}
+
+// SetNMI drives the NMI line, the interrupt is taken on the low to high edge
+func (c *cpu) SetNMI(active bool) {
+	if active && !c.nmiLine {
+		c.nmiPending = true
+	}
+	c.nmiLine = active
+}
+
+// SetIRQ drives the IRQ line for one of the bus.IRQ sources, the interrupt
+// is taken as long as any source holds the line and the interrupt disable
+// flag is clear
+func (c *cpu) SetIRQ(source uint8, active bool) {
+	if active {
+		c.irqLine |= source
+	} else {
+		c.irqLine &= ^source
+	}
+}
+
 // Private Methods
 
 func (c *cpu) setFlag(flag uint8, value bool) {
@@ -368,6 +433,24 @@ func (c *cpu) write(addr uint16, data byte) {
 	c.bus.Write(addr, data)
 }
 
+func (c *cpu) interrupt(vector uint16) {
+	c.write(0x0100+uint16(c.stackPointer), byte((c.programCounter>>8)&0x00FF))
+	c.stackPointer--
+	c.write(0x0100+uint16(c.stackPointer), byte(c.programCounter&0x00FF))
+	c.stackPointer--
+
+	// Hardware interrupts push the status with B clear and U set
+	c.write(0x0100+uint16(c.stackPointer), (c.status & ^c.flags.b)|c.flags.u)
+	c.stackPointer--
+	c.setFlag(c.flags.i, true)
+
+	c.addrAbs = vector
+	lo := c.read(c.addrAbs + 0)
+	hi := c.read(c.addrAbs + 1)
+	c.programCounter = uint16(hi)<<8 | uint16(lo)
+	c.cycles = 7
+}
+
 func (c *cpu) imp() bool {
 	c.fetched = c.accumulator
 	return false
@@ -859,44 +942,6 @@ func (c *cpu) ror() bool {
 	return false
 }
 
-// func (c *cpu) irq() {
-// 	if c.getFlag(c.flags.i) == 0 {
-// 		c.write(0x0100+uint16(c.stackPointer), byte((c.programCounter>>8)&0x00FF))
-// 		c.stackPointer--
-// 		c.write(0x0100+uint16(c.stackPointer), byte(c.programCounter&0x00FF))
-// 		c.stackPointer--
-
-// 		c.setFlag(c.flags.b, false)
-// 		c.setFlag(c.flags.u, true)
-// 		c.setFlag(c.flags.i, true)
-// 		c.write(0x0100+uint16(c.stackPointer), c.status)
-// 		c.stackPointer--
-// 		c.addrAbs = 0xFFFE
-// 		lo := c.read(c.addrAbs + 0)
-// 		hi := c.read(c.addrAbs + 1)
-// 		c.programCounter = (uint16(hi) << 8) | uint16(lo)
-// 		c.cycles = 7
-// 	}
-// }
-
-// func (c *cpu) nmi() {
-// 	c.write(0x0100+uint16(c.stackPointer), byte((c.programCounter>>8)&0x00FF))
-// 	c.stackPointer--
-// 	c.write(0x0100+uint16(c.stackPointer), byte(c.programCounter&0x00FF))
-// 	c.stackPointer--
-
-// 	c.setFlag(c.flags.b, false)
-// 	c.setFlag(c.flags.u, true)
-// 	c.setFlag(c.flags.i, true)
-// 	c.write(0x0100+uint16(c.stackPointer), c.status)
-// 	c.stackPointer--
-// 	c.addrAbs = 0xFFFA
-// 	lo := c.read(c.addrAbs + 0)
-// 	hi := c.read(c.addrAbs + 1)
-// 	c.programCounter = (uint16(hi) << 8) | uint16(lo)
-// 	c.cycles = 8
-// }
-
 func (c *cpu) rti() bool {
 	c.stackPointer++
 	c.status = c.read(0x0100 + uint16(c.stackPointer))
@@ -1001,7 +1046,7 @@ func (c *cpu) brk() bool {
 	c.stackPointer--
 
 	c.setFlag(c.flags.b, true)
-	c.write(0x0100+uint16(c.stackPointer), c.status)
+	c.write(0x0100+uint16(c.stackPointer), c.status|c.flags.u)
 	c.stackPointer--
 	c.setFlag(c.flags.b, false)
 
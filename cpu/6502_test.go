@@ -0,0 +1,193 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/patrickn2/gonesemulator/bus"
+)
+
+// memory is a flat cartridge space holding NOPs and the three vectors
+type memory [0x10000]byte
+
+func (m *memory) CPURead(addr uint16, bReadOnly bool) byte {
+	return m[addr]
+}
+
+func (m *memory) CPUWrite(addr uint16, data byte) {
+	m[addr] = data
+}
+
+func newTestCPU() (*cpu, *bus.BUS) {
+	mem := &memory{}
+	for i := 0x8000; i < 0x10000; i++ {
+		mem[i] = 0xEA // NOP
+	}
+	mem[0xFFFA], mem[0xFFFB] = 0x00, 0x90
+	mem[0xFFFC], mem[0xFFFD] = 0x00, 0x80
+	mem[0xFFFE], mem[0xFFFF] = 0x00, 0xA0
+
+	b := bus.New()
+	b.Attach(bus.CartridgeStart, bus.CartridgeEnd, 0xFFFF, mem)
+	c := New(b)
+	b.ConnectCPU(c)
+	return c, b
+}
+
+// finish runs the cycles left of the current instruction
+func finish(c *cpu) {
+	for c.cycles > 0 {
+		c.Clock()
+	}
+}
+
+// step finishes the current instruction and starts the next one
+func step(c *cpu) {
+	finish(c)
+	c.Clock()
+}
+
+func TestReset(t *testing.T) {
+	c, _ := newTestCPU()
+	c.Reset()
+	if c.programCounter != 0x8000 || c.stackPointer != 0xFD || c.status != c.flags.u|c.flags.i || c.cycles != 7 {
+		t.Errorf("power up reset: PC=$%04X SP=$%02X P=$%02X cycles=%d, want $8000 $FD $24 7",
+			c.programCounter, c.stackPointer, c.status, c.cycles)
+	}
+
+	c.accumulator, c.xRegister, c.yRegister = 0x11, 0x22, 0x33
+	c.stackPointer = 0x50
+	c.status = c.flags.c | c.flags.v | c.flags.u
+	c.programCounter = 0x1234
+	c.Reset()
+	if c.accumulator != 0x11 || c.xRegister != 0x22 || c.yRegister != 0x33 {
+		t.Errorf("reset changed A=$%02X X=$%02X Y=$%02X", c.accumulator, c.xRegister, c.yRegister)
+	}
+	if c.stackPointer != 0x4D {
+		t.Errorf("SP=$%02X, want $4D", c.stackPointer)
+	}
+	if c.status != c.flags.c|c.flags.v|c.flags.u|c.flags.i {
+		t.Errorf("P=$%02X, want only I added to $61", c.status)
+	}
+	if c.programCounter != 0x8000 || c.cycles != 7 {
+		t.Errorf("PC=$%04X cycles=%d, want $8000 7", c.programCounter, c.cycles)
+	}
+}
+
+func TestInterruptPushes(t *testing.T) {
+	tests := []struct {
+		name   string
+		enter  func(c *cpu)
+		vector uint16
+	}{
+		{"IRQ", (*cpu).IRQ, 0xA000},
+		{"NMI", (*cpu).NMI, 0x9000},
+	}
+	for _, tt := range tests {
+		c, b := newTestCPU()
+		c.Reset()
+		c.programCounter = 0x8123
+		c.status = c.flags.b | c.flags.c | c.flags.n
+		tt.enter(c)
+
+		if c.programCounter != tt.vector || c.cycles != 7 || c.stackPointer != 0xFA {
+			t.Errorf("%s: PC=$%04X cycles=%d SP=$%02X, want $%04X 7 $FA",
+				tt.name, c.programCounter, c.cycles, c.stackPointer, tt.vector)
+		}
+		if hi, lo := b.Peek(0x01FD), b.Peek(0x01FC); hi != 0x81 || lo != 0x23 {
+			t.Errorf("%s: pushed PC $%02X%02X, want $8123", tt.name, hi, lo)
+		}
+		if p := b.Peek(0x01FB); p != c.flags.c|c.flags.n|c.flags.u {
+			t.Errorf("%s: pushed P=$%02X, want B clear and U set: $A1", tt.name, p)
+		}
+		if c.getFlag(c.flags.i) == 0 {
+			t.Errorf("%s: I not set in the handler", tt.name)
+		}
+	}
+}
+
+func TestIRQMasking(t *testing.T) {
+	c, _ := newTestCPU()
+	c.Reset()
+	finish(c)
+
+	// Reset leaves I set, a held IRQ line waits
+	c.SetIRQ(bus.IRQMapper, true)
+	step(c)
+	if c.programCounter != 0x8001 {
+		t.Fatalf("IRQ taken with I set, PC=$%04X", c.programCounter)
+	}
+	c.IRQ()
+	if c.programCounter != 0x8001 {
+		t.Fatalf("IRQ() entered with I set, PC=$%04X", c.programCounter)
+	}
+
+	c.setFlag(c.flags.i, false)
+	step(c)
+	if c.programCounter != 0xA000 {
+		t.Fatalf("IRQ not taken once I was cleared, PC=$%04X", c.programCounter)
+	}
+
+	// The handler runs with I set, the level held line does not re-enter
+	step(c)
+	if c.programCounter != 0xA001 {
+		t.Errorf("IRQ re-entered inside its handler, PC=$%04X", c.programCounter)
+	}
+}
+
+func TestIRQSources(t *testing.T) {
+	c, _ := newTestCPU()
+	c.Reset()
+	finish(c)
+	c.setFlag(c.flags.i, false)
+
+	c.SetIRQ(bus.IRQMapper, true)
+	c.SetIRQ(bus.IRQFrameCounter, true)
+	c.SetIRQ(bus.IRQMapper, false)
+	step(c)
+	if c.programCounter != 0xA000 {
+		t.Fatalf("IRQ dropped while the frame counter still holds it, PC=$%04X", c.programCounter)
+	}
+
+	// Level triggered: still held, so it fires again as soon as I clears
+	c.setFlag(c.flags.i, false)
+	step(c)
+	if c.programCounter != 0xA000 {
+		t.Fatalf("held IRQ not taken again, PC=$%04X", c.programCounter)
+	}
+
+	c.SetIRQ(bus.IRQFrameCounter, false)
+	c.setFlag(c.flags.i, false)
+	step(c)
+	if c.programCounter != 0xA001 {
+		t.Errorf("IRQ taken with every source released, PC=$%04X", c.programCounter)
+	}
+}
+
+func TestNMIEdge(t *testing.T) {
+	c, _ := newTestCPU()
+	c.Reset()
+	finish(c)
+
+	c.SetNMI(true)
+	step(c)
+	if c.programCounter != 0x9000 {
+		t.Fatalf("NMI not taken on the rising edge, PC=$%04X", c.programCounter)
+	}
+
+	// Holding the line high is not a new edge
+	c.SetNMI(true)
+	step(c)
+	if c.programCounter != 0x9001 {
+		t.Fatalf("NMI taken again without an edge, PC=$%04X", c.programCounter)
+	}
+
+	// An edge raised mid-instruction is serviced at the next boundary,
+	// even with I set
+	c.SetNMI(false)
+	c.SetNMI(true)
+	c.SetNMI(false)
+	step(c)
+	if c.programCounter != 0x9000 {
+		t.Errorf("latched NMI edge lost, PC=$%04X", c.programCounter)
+	}
+}
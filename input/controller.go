@@ -0,0 +1,58 @@
+package input
+
+// Standard controller buttons, in the order they are shifted out
+const (
+	ButtonA byte = 1 << iota
+	ButtonB
+	ButtonSelect
+	ButtonStart
+	ButtonUp
+	ButtonDown
+	ButtonLeft
+	ButtonRight
+)
+
+// Controller ports answer at $4016 and $4017
+const (
+	Port1 uint16 = 0x4016
+	Port2 uint16 = 0x4017
+)
+
+type controllers struct {
+	buttons [2]byte
+	shift   [2]byte
+	strobe  bool
+}
+
+func New() *controllers {
+	return &controllers{}
+}
+
+// SetButtons stores the buttons currently held on a port (0 or 1)
+func (c *controllers) SetButtons(port int, buttons byte) {
+	c.buttons[port&1] = buttons
+}
+
+func (c *controllers) CPUWrite(addr uint16, data byte) {
+	// $4017 writes belong to the APU frame counter
+	if addr != Port1 {
+		return
+	}
+	c.strobe = data&0x01 != 0
+	if c.strobe {
+		c.shift = c.buttons
+	}
+}
+
+func (c *controllers) CPURead(addr uint16, bReadOnly bool) byte {
+	port := addr & 0x0001
+	if c.strobe {
+		c.shift[port] = c.buttons[port]
+	}
+	data := c.shift[port] & 0x01
+	if !bReadOnly {
+		// Official controllers report 1 once all eight buttons are read
+		c.shift[port] = c.shift[port]>>1 | 0x80
+	}
+	return data
+}
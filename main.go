@@ -1,9 +1,36 @@
 package main
 
 import (
+	"fmt"
+	"log"
+
+	"github.com/patrickn2/gonesemulator/bus"
 	"github.com/patrickn2/gonesemulator/cartridge"
+	"github.com/patrickn2/gonesemulator/cpu"
+	"github.com/patrickn2/gonesemulator/input"
+	"github.com/patrickn2/gonesemulator/ppu"
 )
 
 func main() {
-	cartridge.New("roms/bartman.nes")
+	cart, err := cartridge.New("roms/bartman.nes")
+	if err != nil {
+		log.Fatalln("error loading cartridge", err)
+	}
+	defer cart.Close()
+	fmt.Printf("%+v\n", cart.Header)
+	for _, correction := range cart.Corrections {
+		fmt.Println("header corrected by database:", correction)
+	}
+
+	graphics := ppu.New()
+	graphics.ConnectCartridge(cart)
+
+	nes := bus.New()
+	nes.ConnectPPU(graphics)
+	nes.Attach(input.Port1, input.Port2, 0xFFFF, input.New())
+	nes.ConnectCartridge(cart)
+
+	processor := cpu.New(nes)
+	nes.ConnectCPU(processor)
+	processor.Reset()
 }
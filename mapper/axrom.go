@@ -0,0 +1,77 @@
+package mapper
+
+import (
+	"errors"
+	"io"
+)
+
+// AxROM (mapper 7) switches 32KB of PRG with bits 0-2 of $8000-$FFFF and
+// picks the single nametable page with bit 4. CHR is 8KB of RAM.
+type axrom struct {
+	board
+	regs axromRegisters
+}
+
+type axromRegisters struct {
+	Bank byte
+}
+
+func init() {
+	Register(7, newAxROM)
+}
+
+func newAxROM(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("axrom: missing PRG ROM")
+	}
+	return &axrom{board: newBoard(cfg)}, nil
+}
+
+func (m *axrom) Reset() {
+	m.regs = axromRegisters{}
+}
+
+func (m *axrom) Mirroring() Mirroring {
+	if m.regs.Bank&0x10 != 0 {
+		return SingleScreenHigh
+	}
+	return SingleScreenLow
+}
+
+func (m *axrom) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	if addr >= 0x8000 {
+		return m.readPRG(0x8000, int(m.regs.Bank&0x07), addr), true
+	}
+	return 0x00, false
+}
+
+func (m *axrom) CPUWrite(addr uint16, data byte) bool {
+	if addr >= 0x8000 {
+		rom, _ := m.CPURead(addr, true)
+		m.regs.Bank = m.busConflict(data, rom)
+		return true
+	}
+	return false
+}
+
+func (m *axrom) PPURead(addr uint16) (byte, bool) {
+	if addr <= 0x1FFF {
+		return m.readCHR(0x2000, 0, addr), true
+	}
+	return 0x00, false
+}
+
+func (m *axrom) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF {
+		return m.writeCHR(0x2000, 0, addr, data)
+	}
+	return false
+}
+
+func (m *axrom) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *axrom) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
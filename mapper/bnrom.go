@@ -0,0 +1,118 @@
+package mapper
+
+import (
+	"errors"
+	"io"
+)
+
+// Mapper 34 covers two unrelated boards with 32KB PRG banking. BNROM
+// (submapper 2) latches the bank from writes to $8000-$FFFF, with bus
+// conflicts, and has CHR RAM. NINA-001 (submapper 1) has 8KB of PRG RAM
+// and banks through $7FFD-$7FFF, two 4KB CHR banks included. Submapper 0
+// assumes NINA-001 when there is more than 8KB of CHR ROM, and a BNROM
+// without bus conflicts otherwise.
+type bnrom struct {
+	board
+	nina001 bool
+	regs    bnromRegisters
+}
+
+type bnromRegisters struct {
+	PRGBank  byte
+	CHRBanks [2]byte
+}
+
+const (
+	bnromSubmapperNINA001 = 1
+	bnromSubmapperBNROM   = 2
+)
+
+func init() {
+	Register(34, newBNROM)
+}
+
+func newBNROM(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("bnrom: missing PRG ROM")
+	}
+	m := &bnrom{board: newBoard(cfg)}
+	switch cfg.Submapper {
+	case bnromSubmapperNINA001:
+		m.nina001 = true
+	case bnromSubmapperBNROM:
+	default:
+		m.nina001 = len(cfg.CHR) > 0x2000
+	}
+	return m, nil
+}
+
+func (m *bnrom) Reset() {
+	m.regs = bnromRegisters{}
+}
+
+func (m *bnrom) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	switch {
+	case addr >= 0x8000:
+		return m.readPRG(0x8000, int(m.regs.PRGBank), addr), true
+	case addr >= 0x6000:
+		return m.readPRGRAM(addr)
+	}
+	return 0x00, false
+}
+
+func (m *bnrom) CPUWrite(addr uint16, data byte) bool {
+	switch {
+	case addr >= 0x8000:
+		if m.nina001 {
+			return false
+		}
+		rom, _ := m.CPURead(addr, true)
+		m.regs.PRGBank = m.busConflict(data, rom)
+		return true
+	case addr >= 0x6000:
+		if m.nina001 {
+			switch addr {
+			case 0x7FFD:
+				m.regs.PRGBank = data & 0x01
+			case 0x7FFE:
+				m.regs.CHRBanks[0] = data & 0x0F
+			case 0x7FFF:
+				m.regs.CHRBanks[1] = data & 0x0F
+			}
+		}
+		return m.writePRGRAM(addr, data)
+	}
+	return false
+}
+
+func (m *bnrom) PPURead(addr uint16) (byte, bool) {
+	if addr <= 0x1FFF {
+		return m.readCHR(0x1000, m.chrBank(addr), addr), true
+	}
+	return 0x00, false
+}
+
+func (m *bnrom) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF {
+		return m.writeCHR(0x1000, m.chrBank(addr), addr, data)
+	}
+	return false
+}
+
+func (m *bnrom) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *bnrom) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
+
+// Private Methods
+
+// chrBank returns the 4KB bank at addr, BNROM CHR is not banked
+func (m *bnrom) chrBank(addr uint16) int {
+	if !m.nina001 {
+		return int(addr / 0x1000)
+	}
+	return int(m.regs.CHRBanks[addr/0x1000])
+}
@@ -0,0 +1,27 @@
+package mapper
+
+import "testing"
+
+func TestBNROMBusConflicts(t *testing.T) {
+	tests := []struct {
+		submapper uint8
+		want      byte
+	}{
+		{0, 3},
+		{bnromSubmapperBNROM, 2},
+	}
+	for _, tt := range tests {
+		// Four 32KB banks holding their number, but for a $02 at $8000
+		prg := make([]byte, 0x20000)
+		for i := range prg {
+			prg[i] = byte(i / 0x8000)
+		}
+		prg[0] = 0x02
+		m, _ := New(34, Config{Submapper: tt.submapper, PRG: prg})
+
+		m.CPUWrite(0x8000, 0x03)
+		if got := cpuRead(m, 0x8001); got != tt.want {
+			t.Errorf("submapper %d: bank %d, want %d", tt.submapper, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package mapper
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Discrete logic boards (mappers 2, 3 and 7) share their NES 2.0
+// submappers, 1 has no bus conflicts and 2 has AND-type bus conflicts
+const submapperBusConflicts = 2
+
+// board holds what every cartridge has in common, the memories and the
+// header mirroring. Mappers embed it and override what they need.
+type board struct {
+	submapper uint8
+	prg       []byte
+	chr       []byte
+	chrRAM    bool
+	prgRAM    []byte
+	trainer   []byte
+	mirroring Mirroring
+	battery   bool
+}
+
+func newBoard(cfg Config) board {
+	// A trainer needs $7000-$71FF, smaller RAM would mirror over it
+	ramSize := cfg.PRGRAMSize
+	if len(cfg.Trainer) > 0 && ramSize < 0x2000 {
+		ramSize = 0x2000
+	}
+	b := board{
+		submapper: cfg.Submapper,
+		prg:       cfg.PRG,
+		chr:       cfg.CHR,
+		prgRAM:    make([]byte, ramSize),
+		trainer:   cfg.Trainer,
+		mirroring: cfg.Mirroring,
+		battery:   cfg.Battery,
+	}
+	if len(b.chr) == 0 {
+		b.chr = make([]byte, max(cfg.CHRRAMSize, 0x2000))
+		b.chrRAM = true
+	}
+	b.placeTrainer()
+	return b
+}
+
+func (b *board) Mirroring() Mirroring {
+	return b.mirroring
+}
+
+func (b *board) IRQ() bool {
+	return false
+}
+
+func (b *board) Clock() {}
+
+func (b *board) Reset() {}
+
+func (b *board) BatteryRAM() []byte {
+	if !b.battery {
+		return nil
+	}
+	return b.prgRAM
+}
+
+// Private Methods
+
+// busConflict resolves a register write on discrete logic boards that keep
+// ROM driving the data bus during writes. NES 2.0 submapper 2 marks such
+// boards, and the register then latches the AND of both values.
+func (b *board) busConflict(data byte, rom byte) byte {
+	if b.submapper == submapperBusConflicts {
+		return data & rom
+	}
+	return data
+}
+
+// prgBanks is the number of banks of size bytes in PRG ROM
+func (b *board) prgBanks(size int) int {
+	return max(len(b.prg)/size, 1)
+}
+
+// chrBanks is the number of banks of size bytes in CHR memory
+func (b *board) chrBanks(size int) int {
+	return max(len(b.chr)/size, 1)
+}
+
+// readPRG reads addr inside a bank of the given size, bank numbers past
+// the end of the ROM wrap around the way unconnected address lines do
+func (b *board) readPRG(size int, bank int, addr uint16) byte {
+	if len(b.prg) == 0 {
+		return 0x00
+	}
+	return b.prg[(bank*size+int(addr)%size)%len(b.prg)]
+}
+
+func (b *board) chrOffset(size int, bank int, addr uint16) int {
+	return (bank*size + int(addr)%size) % len(b.chr)
+}
+
+func (b *board) readCHR(size int, bank int, addr uint16) byte {
+	if len(b.chr) == 0 {
+		return 0x00
+	}
+	return b.chr[b.chrOffset(size, bank, addr)]
+}
+
+// writeCHR only lands when the board has CHR RAM
+func (b *board) writeCHR(size int, bank int, addr uint16, data byte) bool {
+	if !b.chrRAM {
+		return false
+	}
+	b.chr[b.chrOffset(size, bank, addr)] = data
+	return true
+}
+
+// readPRGRAM and writePRGRAM access $6000-$7FFF, mirrored over the RAM
+func (b *board) readPRGRAM(addr uint16) (byte, bool) {
+	if len(b.prgRAM) == 0 {
+		return 0x00, false
+	}
+	return b.prgRAM[int(addr-0x6000)%len(b.prgRAM)], true
+}
+
+func (b *board) writePRGRAM(addr uint16, data byte) bool {
+	if len(b.prgRAM) == 0 {
+		return false
+	}
+	b.prgRAM[int(addr-0x6000)%len(b.prgRAM)] = data
+	return true
+}
+
+// placeTrainer copies the trainer to where $7000 lands in PRG RAM, mappers
+// replacing the RAM call it again
+func (b *board) placeTrainer() {
+	if len(b.trainer) == 0 || len(b.prgRAM) == 0 {
+		return
+	}
+	copy(b.prgRAM[0x1000%len(b.prgRAM):], b.trainer)
+}
+
+// save and load write the common board state followed by the mapper
+// registers, which must be a pointer to a fixed size value
+func (b *board) save(w io.Writer, regs any) error {
+	if err := binary.Write(w, binary.LittleEndian, b.prgRAM); err != nil {
+		return err
+	}
+	if b.chrRAM {
+		if err := binary.Write(w, binary.LittleEndian, b.chr); err != nil {
+			return err
+		}
+	}
+	if err := binary.Write(w, binary.LittleEndian, b.mirroring); err != nil {
+		return err
+	}
+	if regs == nil {
+		return nil
+	}
+	return binary.Write(w, binary.LittleEndian, regs)
+}
+
+func (b *board) load(r io.Reader, regs any) error {
+	if err := binary.Read(r, binary.LittleEndian, b.prgRAM); err != nil {
+		return err
+	}
+	if b.chrRAM {
+		if err := binary.Read(r, binary.LittleEndian, b.chr); err != nil {
+			return err
+		}
+	}
+	if err := binary.Read(r, binary.LittleEndian, &b.mirroring); err != nil {
+		return err
+	}
+	if regs == nil {
+		return nil
+	}
+	return binary.Read(r, binary.LittleEndian, regs)
+}
@@ -0,0 +1,99 @@
+package mapper
+
+import (
+	"errors"
+	"io"
+)
+
+// Camerica/Codemasters boards (mapper 71) switch 16KB of PRG at $8000
+// through $C000-$FFFF and fix the last bank at $C000. The Fire Hawk
+// board (submapper 1) also selects a single screen page with bit 4 of
+// writes to $8000-$9FFF. Submapper 0 follows that register once a game
+// writes it, which the other boards' games never do.
+type camerica struct {
+	board
+	regs camericaRegisters
+}
+
+type camericaRegisters struct {
+	PRGBank       byte
+	Mirroring     byte
+	MirroringUsed bool
+}
+
+const camericaSubmapperFireHawk = 1
+
+func init() {
+	Register(71, newCamerica)
+}
+
+func newCamerica(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("camerica: missing PRG ROM")
+	}
+	m := &camerica{board: newBoard(cfg)}
+	m.Reset()
+	return m, nil
+}
+
+func (m *camerica) Reset() {
+	m.regs = camericaRegisters{
+		MirroringUsed: m.submapper == camericaSubmapperFireHawk,
+	}
+}
+
+func (m *camerica) Mirroring() Mirroring {
+	if !m.regs.MirroringUsed {
+		return m.mirroring
+	}
+	if m.regs.Mirroring&0x10 != 0 {
+		return SingleScreenHigh
+	}
+	return SingleScreenLow
+}
+
+func (m *camerica) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	switch {
+	case addr >= 0xC000:
+		return m.readPRG(0x4000, m.prgBanks(0x4000)-1, addr), true
+	case addr >= 0x8000:
+		return m.readPRG(0x4000, int(m.regs.PRGBank), addr), true
+	}
+	return 0x00, false
+}
+
+func (m *camerica) CPUWrite(addr uint16, data byte) bool {
+	switch {
+	case addr >= 0xC000:
+		m.regs.PRGBank = data
+	case addr >= 0x8000 && addr <= 0x9FFF:
+		m.regs.Mirroring = data
+		m.regs.MirroringUsed = true
+	case addr >= 0x8000:
+	default:
+		return false
+	}
+	return true
+}
+
+func (m *camerica) PPURead(addr uint16) (byte, bool) {
+	if addr <= 0x1FFF {
+		return m.readCHR(0x2000, 0, addr), true
+	}
+	return 0x00, false
+}
+
+func (m *camerica) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF {
+		return m.writeCHR(0x2000, 0, addr, data)
+	}
+	return false
+}
+
+func (m *camerica) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *camerica) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
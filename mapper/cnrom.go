@@ -0,0 +1,76 @@
+package mapper
+
+import (
+	"errors"
+	"io"
+)
+
+// CNROM (mapper 3) has fixed PRG, 16KB mirrored or 32KB like NROM, and
+// switches 8KB of CHR ROM through a register at $8000-$FFFF
+type cnrom struct {
+	board
+	regs cnromRegisters
+}
+
+type cnromRegisters struct {
+	CHRBank byte
+}
+
+func init() {
+	Register(3, newCNROM)
+}
+
+func newCNROM(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("cnrom: missing PRG ROM")
+	}
+	return &cnrom{board: newBoard(cfg)}, nil
+}
+
+func (m *cnrom) Reset() {
+	m.regs = cnromRegisters{}
+}
+
+func (m *cnrom) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	switch {
+	case addr >= 0x8000:
+		return m.readPRG(0x8000, 0, addr), true
+	case addr >= 0x6000:
+		return m.readPRGRAM(addr)
+	}
+	return 0x00, false
+}
+
+func (m *cnrom) CPUWrite(addr uint16, data byte) bool {
+	switch {
+	case addr >= 0x8000:
+		rom, _ := m.CPURead(addr, true)
+		m.regs.CHRBank = m.busConflict(data, rom)
+		return true
+	case addr >= 0x6000:
+		return m.writePRGRAM(addr, data)
+	}
+	return false
+}
+
+func (m *cnrom) PPURead(addr uint16) (byte, bool) {
+	if addr <= 0x1FFF {
+		return m.readCHR(0x2000, int(m.regs.CHRBank), addr), true
+	}
+	return 0x00, false
+}
+
+func (m *cnrom) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF {
+		return m.writeCHR(0x2000, int(m.regs.CHRBank), addr, data)
+	}
+	return false
+}
+
+func (m *cnrom) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *cnrom) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
@@ -0,0 +1,69 @@
+package mapper
+
+import (
+	"errors"
+	"io"
+)
+
+// Color Dreams (mapper 11) switches 32KB of PRG with bits 0-1 and 8KB of
+// CHR with bits 4-7 of a register at $8000-$FFFF
+type colorDreams struct {
+	board
+	regs colorDreamsRegisters
+}
+
+type colorDreamsRegisters struct {
+	Bank byte
+}
+
+func init() {
+	Register(11, newColorDreams)
+}
+
+func newColorDreams(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("colordreams: missing PRG ROM")
+	}
+	return &colorDreams{board: newBoard(cfg)}, nil
+}
+
+func (m *colorDreams) Reset() {
+	m.regs = colorDreamsRegisters{}
+}
+
+func (m *colorDreams) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	if addr >= 0x8000 {
+		return m.readPRG(0x8000, int(m.regs.Bank&0x03), addr), true
+	}
+	return 0x00, false
+}
+
+func (m *colorDreams) CPUWrite(addr uint16, data byte) bool {
+	if addr >= 0x8000 {
+		m.regs.Bank = data
+		return true
+	}
+	return false
+}
+
+func (m *colorDreams) PPURead(addr uint16) (byte, bool) {
+	if addr <= 0x1FFF {
+		return m.readCHR(0x2000, int(m.regs.Bank>>4), addr), true
+	}
+	return 0x00, false
+}
+
+func (m *colorDreams) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF {
+		return m.writeCHR(0x2000, int(m.regs.Bank>>4), addr, data)
+	}
+	return false
+}
+
+func (m *colorDreams) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *colorDreams) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
@@ -0,0 +1,148 @@
+package mapper
+
+import (
+	"errors"
+	"io"
+)
+
+// Sunsoft FME-7 and 5B (mapper 69) are programmed through a command
+// register at $8000 and a parameter register at $A000. Commands 0-7 set
+// the 1KB CHR banks, 8 the $6000 bank, which may be ROM or RAM, 9-B the
+// 8KB PRG banks and C the mirroring. D-F drive a 16 bit counter that
+// decrements every CPU cycle and raises an IRQ when it wraps. The 5B
+// expansion audio is not emulated.
+type fme7 struct {
+	board
+	regs fme7Registers
+}
+
+type fme7Registers struct {
+	Command    byte
+	CHRBanks   [8]byte
+	PRGBanks   [4]byte // $6000, $8000, $A000, $C000
+	Mirroring  byte
+	IRQControl byte
+	IRQCounter uint16
+	IRQPending bool
+}
+
+func init() {
+	Register(69, newFME7)
+}
+
+func newFME7(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("fme7: missing PRG ROM")
+	}
+	return &fme7{board: newBoard(cfg)}, nil
+}
+
+func (m *fme7) Reset() {
+	m.regs = fme7Registers{}
+}
+
+func (m *fme7) Clock() {
+	if m.regs.IRQControl&0x80 == 0 {
+		return
+	}
+	m.regs.IRQCounter--
+	if m.regs.IRQCounter == 0xFFFF && m.regs.IRQControl&0x01 != 0 {
+		m.regs.IRQPending = true
+	}
+}
+
+func (m *fme7) IRQ() bool {
+	return m.regs.IRQPending
+}
+
+func (m *fme7) Mirroring() Mirroring {
+	return vrcMirroring(m.regs.Mirroring)
+}
+
+func (m *fme7) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	switch {
+	case addr >= 0xE000:
+		return m.readPRG(0x2000, m.prgBanks(0x2000)-1, addr), true
+	case addr >= 0x8000:
+		bank := m.regs.PRGBanks[1+(addr-0x8000)/0x2000]
+		return m.readPRG(0x2000, int(bank&0x3F), addr), true
+	case addr >= 0x6000:
+		bank := m.regs.PRGBanks[0]
+		switch {
+		case bank&0x40 == 0:
+			return m.readPRG(0x2000, int(bank&0x3F), addr), true
+		case bank&0x80 == 0 || len(m.prgRAM) == 0:
+			return 0x00, false
+		}
+		return m.prgRAM[m.prgRAMOffset(addr)], true
+	}
+	return 0x00, false
+}
+
+func (m *fme7) CPUWrite(addr uint16, data byte) bool {
+	switch {
+	case addr >= 0xC000:
+		// 5B audio registers
+		return true
+	case addr >= 0xA000:
+		m.writeParameter(data)
+		return true
+	case addr >= 0x8000:
+		m.regs.Command = data & 0x0F
+		return true
+	case addr >= 0x6000:
+		if m.regs.PRGBanks[0]&0xC0 != 0xC0 || len(m.prgRAM) == 0 {
+			return false
+		}
+		m.prgRAM[m.prgRAMOffset(addr)] = data
+		return true
+	}
+	return false
+}
+
+func (m *fme7) PPURead(addr uint16) (byte, bool) {
+	if addr <= 0x1FFF {
+		return m.readCHR(0x0400, int(m.regs.CHRBanks[addr/0x0400]), addr), true
+	}
+	return 0x00, false
+}
+
+func (m *fme7) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF {
+		return m.writeCHR(0x0400, int(m.regs.CHRBanks[addr/0x0400]), addr, data)
+	}
+	return false
+}
+
+func (m *fme7) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *fme7) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
+
+// Private Methods
+
+func (m *fme7) writeParameter(data byte) {
+	switch command := m.regs.Command; {
+	case command <= 0x07:
+		m.regs.CHRBanks[command] = data
+	case command <= 0x0B:
+		m.regs.PRGBanks[command-0x08] = data
+	case command == 0x0C:
+		m.regs.Mirroring = data & 0x03
+	case command == 0x0D:
+		m.regs.IRQControl = data
+		m.regs.IRQPending = false
+	case command == 0x0E:
+		m.regs.IRQCounter = m.regs.IRQCounter&0xFF00 | uint16(data)
+	case command == 0x0F:
+		m.regs.IRQCounter = m.regs.IRQCounter&0x00FF | uint16(data)<<8
+	}
+}
+
+func (m *fme7) prgRAMOffset(addr uint16) int {
+	bank := int(m.regs.PRGBanks[0] & 0x3F)
+	return (bank*0x2000 + int(addr-0x6000)) % len(m.prgRAM)
+}
@@ -0,0 +1,69 @@
+package mapper
+
+import (
+	"errors"
+	"io"
+)
+
+// GxROM and MHROM (mapper 66) switch 32KB of PRG with bits 4-5 and 8KB of
+// CHR with bits 0-1 of a register at $8000-$FFFF
+type gxrom struct {
+	board
+	regs gxromRegisters
+}
+
+type gxromRegisters struct {
+	Bank byte
+}
+
+func init() {
+	Register(66, newGxROM)
+}
+
+func newGxROM(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("gxrom: missing PRG ROM")
+	}
+	return &gxrom{board: newBoard(cfg)}, nil
+}
+
+func (m *gxrom) Reset() {
+	m.regs = gxromRegisters{}
+}
+
+func (m *gxrom) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	if addr >= 0x8000 {
+		return m.readPRG(0x8000, int(m.regs.Bank>>4)&0x03, addr), true
+	}
+	return 0x00, false
+}
+
+func (m *gxrom) CPUWrite(addr uint16, data byte) bool {
+	if addr >= 0x8000 {
+		m.regs.Bank = data
+		return true
+	}
+	return false
+}
+
+func (m *gxrom) PPURead(addr uint16) (byte, bool) {
+	if addr <= 0x1FFF {
+		return m.readCHR(0x2000, int(m.regs.Bank&0x03), addr), true
+	}
+	return 0x00, false
+}
+
+func (m *gxrom) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF {
+		return m.writeCHR(0x2000, int(m.regs.Bank&0x03), addr, data)
+	}
+	return false
+}
+
+func (m *gxrom) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *gxrom) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
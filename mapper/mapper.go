@@ -1,17 +1,141 @@
 package mapper
 
-var nPRGBanks uint8 = 0
-var nCHRBanks uint8 = 0
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
 
-func CPUMapRead(addr uint16, mapped_addr uint32) bool {
-	return false
+// Mirroring tells which CIRAM page answers each of the four nametables.
+// Two bits per nametable, $2000 in the lowest bits, which is also the
+// layout MMC5 uses for its nametable mapping register.
+type Mirroring uint8
+
+const (
+	Horizontal       Mirroring = 0b01_01_00_00
+	Vertical         Mirroring = 0b01_00_01_00
+	SingleScreenLow  Mirroring = 0b00_00_00_00
+	SingleScreenHigh Mirroring = 0b01_01_01_01
+	FourScreen       Mirroring = 0b11_10_01_00
+)
+
+func (m Mirroring) String() string {
+	switch m {
+	case Horizontal:
+		return "horizontal"
+	case Vertical:
+		return "vertical"
+	case SingleScreenLow:
+		return "single screen A"
+	case SingleScreenHigh:
+		return "single screen B"
+	case FourScreen:
+		return "four screen"
+	}
+	return fmt.Sprintf("custom %08b", uint8(m))
+}
+
+// Page is the nametable page answering a $2000-$2FFF address. Pages 0 and
+// 1 are the console CIRAM, 2 and 3 the extra VRAM of four-screen boards.
+func (m Mirroring) Page(addr uint16) int {
+	return int(m>>(((addr>>10)&0x03)*2)) & 0x03
+}
+
+// Mapper is the logic on the cartridge board sitting between the CPU and
+// PPU buses and the PRG and CHR memories. Reads and writes report whether
+// the mapper answered the address, CPU reads with bReadOnly set must not
+// change any state.
+type Mapper interface {
+	CPURead(addr uint16, bReadOnly bool) (byte, bool)
+	CPUWrite(addr uint16, data byte) bool
+	PPURead(addr uint16) (byte, bool)
+	PPUWrite(addr uint16, data byte) bool
+
+	// Mirroring is the current nametable arrangement, boards may change it
+	// at any time
+	Mirroring() Mirroring
+	// IRQ is the level of the cartridge /IRQ line
+	IRQ() bool
+
+	// Clock is called once per CPU cycle, scanline counters watch the PPU
+	// bus themselves
+	Clock()
+
+	// BatteryRAM is the memory kept alive by the battery, nil when the
+	// board has none. It aliases the live memory.
+	BatteryRAM() []byte
+
+	Reset()
+	SaveState(w io.Writer) error
+	LoadState(r io.Reader) error
+}
+
+// Snooper is implemented by mappers that watch CPU writes to the PPU
+// registers, reg being $0000-$0007
+type Snooper interface {
+	SnoopPPU(reg uint16, data byte)
+}
+
+// Config describes the board a mapper is instantiated for. A Trainer is
+// copied into PRG RAM at $7000, which is grown to 8KB for it if needed. Boards
+// without CHR ROM get CHRRAMSize bytes of CHR RAM, 8KB when unknown.
+type Config struct {
+	Submapper  uint8
+	PRG        []byte
+	CHR        []byte
+	Trainer    []byte
+	PRGRAMSize int
+	CHRRAMSize int
+	Mirroring  Mirroring
+	Battery    bool
 }
-func CPUMapWrite(addr uint16, mapped_addr uint32) bool {
-	return false
+
+// Constructor builds a mapper for a board
+type Constructor func(cfg Config) (Mapper, error)
+
+// ErrUnsupported matches every UnsupportedError with errors.Is
+var ErrUnsupported = errors.New("unsupported mapper")
+
+// UnsupportedError is returned by New for mapper numbers nobody registered
+type UnsupportedError struct {
+	ID uint16
 }
-func PPUMapRead(addr uint16, mapped_addr uint32) bool {
-	return false
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("unsupported mapper %d", e.ID)
+}
+
+func (e *UnsupportedError) Is(target error) bool {
+	return target == ErrUnsupported
 }
-func PPUMapWrite(addr uint16, mapped_addr uint32) bool {
-	return false
+
+var constructors = map[uint16]Constructor{}
+
+// Register makes a mapper implementation available under its iNES number.
+// It is meant to be called from init and panics on duplicates.
+func Register(id uint16, constructor Constructor) {
+	if _, ok := constructors[id]; ok {
+		panic(fmt.Sprintf("mapper %d registered twice", id))
+	}
+	constructors[id] = constructor
+}
+
+// New instantiates the mapper registered under id
+func New(id uint16, cfg Config) (Mapper, error) {
+	constructor, ok := constructors[id]
+	if !ok {
+		return nil, &UnsupportedError{ID: id}
+	}
+	return constructor(cfg)
+}
+
+// Supported lists the registered mapper numbers in ascending order
+func Supported() []uint16 {
+	ids := make([]uint16, 0, len(constructors))
+	for id := range constructors {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
 }
@@ -0,0 +1,208 @@
+package mapper
+
+import (
+	"errors"
+	"io"
+)
+
+// MMC1 (mapper 1) is loaded one bit at a time through a 5 bit shift
+// register at $8000-$FFFF, the fifth write copies it to the register
+// selected by address bits 13 and 14. Boards with more memory than the
+// chip can address reuse the CHR bank bits:
+//
+//	SNROM  8KB CHR RAM, CHR bit 4 disables PRG RAM
+//	SOROM  16KB PRG RAM, CHR bit 3 selects the RAM bank
+//	SUROM  512KB PRG, CHR bit 4 selects the 256KB PRG half
+//	SXROM  512KB PRG and 32KB PRG RAM, CHR bits 2-3 select the RAM bank
+//
+// SEROM, SHROM and SH1ROM (submapper 5) wire 32KB of PRG ROM straight to
+// the CPU and ignore the PRG bank register.
+type mmc1 struct {
+	board
+	regs mmc1Registers
+}
+
+type mmc1Registers struct {
+	Shift      byte
+	ShiftCount byte
+	Control    byte
+	CHRBank0   byte
+	CHRBank1   byte
+	PRGBank    byte
+}
+
+const mmc1SubmapperSEROM = 5
+
+func init() {
+	Register(1, newMMC1)
+}
+
+func newMMC1(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("mmc1: missing PRG ROM")
+	}
+	m := &mmc1{board: newBoard(cfg)}
+	m.Reset()
+	return m, nil
+}
+
+func (m *mmc1) Reset() {
+	m.regs = mmc1Registers{Control: 0x0C}
+}
+
+func (m *mmc1) Mirroring() Mirroring {
+	switch m.regs.Control & 0x03 {
+	case 0:
+		return SingleScreenLow
+	case 1:
+		return SingleScreenHigh
+	case 2:
+		return Vertical
+	}
+	return Horizontal
+}
+
+func (m *mmc1) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	switch {
+	case addr >= 0x8000:
+		return m.readPRG(0x4000, m.prgBank(addr), addr), true
+	case addr >= 0x6000:
+		if !m.prgRAMEnabled() {
+			return 0x00, false
+		}
+		return m.prgRAM[m.prgRAMOffset(addr)], true
+	}
+	return 0x00, false
+}
+
+func (m *mmc1) CPUWrite(addr uint16, data byte) bool {
+	switch {
+	case addr >= 0x8000:
+		m.writeRegister(addr, data)
+		return true
+	case addr >= 0x6000:
+		if !m.prgRAMEnabled() {
+			return false
+		}
+		m.prgRAM[m.prgRAMOffset(addr)] = data
+		return true
+	}
+	return false
+}
+
+func (m *mmc1) PPURead(addr uint16) (byte, bool) {
+	if addr <= 0x1FFF {
+		return m.chr[m.chrOffset(addr)], true
+	}
+	return 0x00, false
+}
+
+func (m *mmc1) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF && m.chrRAM {
+		m.chr[m.chrOffset(addr)] = data
+		return true
+	}
+	return false
+}
+
+func (m *mmc1) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *mmc1) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
+
+// Private Methods
+
+func (m *mmc1) writeRegister(addr uint16, data byte) {
+	if data&0x80 != 0 {
+		m.regs.Shift = 0x00
+		m.regs.ShiftCount = 0
+		m.regs.Control |= 0x0C
+		return
+	}
+
+	m.regs.Shift |= (data & 0x01) << m.regs.ShiftCount
+	m.regs.ShiftCount++
+	if m.regs.ShiftCount < 5 {
+		return
+	}
+
+	switch (addr >> 13) & 0x03 {
+	case 0: // $8000-$9FFF
+		m.regs.Control = m.regs.Shift
+	case 1: // $A000-$BFFF
+		m.regs.CHRBank0 = m.regs.Shift
+	case 2: // $C000-$DFFF
+		m.regs.CHRBank1 = m.regs.Shift
+	case 3: // $E000-$FFFF
+		m.regs.PRGBank = m.regs.Shift
+	}
+	m.regs.Shift = 0x00
+	m.regs.ShiftCount = 0
+}
+
+// prgBank returns the 16KB bank mapped at addr
+func (m *mmc1) prgBank(addr uint16) int {
+	if m.submapper == mmc1SubmapperSEROM {
+		return int(addr-0x8000) / 0x4000
+	}
+
+	bank := int(m.regs.PRGBank & 0x0F)
+
+	// SUROM and SXROM pick the 256KB half with CHR bank bit 4
+	outer := 0
+	if len(m.prg) > 0x40000 {
+		outer = int(m.regs.CHRBank0 & 0x10)
+	}
+
+	switch (m.regs.Control >> 2) & 0x03 {
+	case 0, 1: // 32KB at $8000
+		bank &= 0x0E
+		if addr >= 0xC000 {
+			bank++
+		}
+	case 2: // first bank fixed at $8000
+		if addr < 0xC000 {
+			bank = 0
+		}
+	case 3: // last bank fixed at $C000
+		if addr >= 0xC000 {
+			bank = 0x0F
+		}
+	}
+	return outer | bank
+}
+
+func (m *mmc1) chrOffset(addr uint16) int {
+	if m.regs.Control&0x10 == 0 {
+		return m.board.chrOffset(0x2000, int(m.regs.CHRBank0>>1), addr)
+	}
+	if addr < 0x1000 {
+		return m.board.chrOffset(0x1000, int(m.regs.CHRBank0), addr)
+	}
+	return m.board.chrOffset(0x1000, int(m.regs.CHRBank1), addr)
+}
+
+func (m *mmc1) prgRAMEnabled() bool {
+	if len(m.prgRAM) == 0 || m.regs.PRGBank&0x10 != 0 {
+		return false
+	}
+	// SNROM wires CHR bank bit 4 to a second PRG RAM enable
+	if m.chrRAM && len(m.chr) == 0x2000 && len(m.prg) <= 0x40000 && m.regs.CHRBank0&0x10 != 0 {
+		return false
+	}
+	return true
+}
+
+func (m *mmc1) prgRAMOffset(addr uint16) int {
+	bank := 0
+	switch len(m.prgRAM) {
+	case 0x4000: // SOROM
+		bank = int(m.regs.CHRBank0>>3) & 0x01
+	case 0x8000: // SXROM
+		bank = int(m.regs.CHRBank0>>2) & 0x03
+	}
+	return (bank*0x2000 + int(addr-0x6000)) % len(m.prgRAM)
+}
@@ -0,0 +1,48 @@
+package mapper
+
+import "testing"
+
+// mmc1Write loads value into an MMC1 register through the serial port
+func mmc1Write(m Mapper, addr uint16, value byte) {
+	for i := 0; i < 5; i++ {
+		m.CPUWrite(addr, value>>i&0x01)
+	}
+}
+
+func TestMMC1PRGBanking(t *testing.T) {
+	tests := []struct {
+		submapper    uint8
+		low, high    byte
+		switchedLow  byte
+		switchedHigh byte
+	}{
+		{0, 0, 30, 6, 30},
+		{mmc1SubmapperSEROM, 0, 2, 0, 2},
+	}
+	for _, tt := range tests {
+		cfg := bankedConfig(tt.submapper)
+		cfg.PRG = cfg.PRG[:0x40000]
+		m, err := New(1, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := [2]byte{cpuRead(m, 0x8000), cpuRead(m, 0xC000)}, [2]byte{tt.low, tt.high}; got != want {
+			t.Errorf("submapper %d: banks %v after reset, want %v", tt.submapper, got, want)
+		}
+		mmc1Write(m, 0xE000, 0x03)
+		if got, want := [2]byte{cpuRead(m, 0x8000), cpuRead(m, 0xC000)}, [2]byte{tt.switchedLow, tt.switchedHigh}; got != want {
+			t.Errorf("submapper %d: banks %v after a PRG bank write, want %v", tt.submapper, got, want)
+		}
+	}
+}
+
+func TestMMC1ShiftReset(t *testing.T) {
+	m, _ := New(1, bankedConfig(0))
+	m.CPUWrite(0xE000, 0x01)
+	m.CPUWrite(0xE000, 0x01)
+	m.CPUWrite(0x8000, 0x80)
+	mmc1Write(m, 0xE000, 0x02)
+	if got := cpuRead(m, 0x8000); got != 4 {
+		t.Errorf("$8000 bank %d after a reset write, want 4", got)
+	}
+}
@@ -0,0 +1,151 @@
+package mapper
+
+import (
+	"errors"
+	"io"
+)
+
+// MMC2 (mapper 9) and MMC4 (mapper 10) pick each 4KB CHR half from two
+// banks through a latch the PPU flips by fetching tile $FD or $FE, which
+// lets games change CHR mid-screen without IRQs. The fetch that flips a
+// latch still uses the old bank.
+//
+// MMC2 switches 8KB of PRG at $8000 with the last three banks fixed, and
+// only sets the first latch on the exact addresses $0FD8 and $0FE8. MMC4
+// switches 16KB at $8000, fixes the last bank and adds 8KB of PRG RAM.
+type mmc2 struct {
+	board
+	mmc4 bool
+	regs mmc2Registers
+}
+
+type mmc2Registers struct {
+	PRGBank byte
+	// CHR banks in $B000-$E000 order: $0000 for $FD and $FE, then $1000
+	CHRBanks  [4]byte
+	Mirroring byte
+	Latches   [2]byte
+}
+
+func init() {
+	Register(9, newMMC2)
+	Register(10, newMMC4)
+}
+
+func newMMC2(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("mmc2: missing PRG ROM")
+	}
+	m := &mmc2{board: newBoard(cfg)}
+	m.Reset()
+	return m, nil
+}
+
+func newMMC4(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("mmc4: missing PRG ROM")
+	}
+	m := &mmc2{board: newBoard(cfg), mmc4: true}
+	m.Reset()
+	return m, nil
+}
+
+func (m *mmc2) Reset() {
+	m.regs = mmc2Registers{Latches: [2]byte{0xFE, 0xFE}}
+}
+
+func (m *mmc2) Mirroring() Mirroring {
+	if m.regs.Mirroring&0x01 != 0 {
+		return Horizontal
+	}
+	return Vertical
+}
+
+func (m *mmc2) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	switch {
+	case addr >= 0x8000:
+		if m.mmc4 {
+			bank := m.prgBanks(0x4000) - 1
+			if addr < 0xC000 {
+				bank = int(m.regs.PRGBank & 0x0F)
+			}
+			return m.readPRG(0x4000, bank, addr), true
+		}
+		bank := m.prgBanks(0x2000) - 4 + int(addr-0x8000)/0x2000
+		if addr < 0xA000 {
+			bank = int(m.regs.PRGBank & 0x0F)
+		}
+		return m.readPRG(0x2000, bank, addr), true
+	case addr >= 0x6000:
+		return m.readPRGRAM(addr)
+	}
+	return 0x00, false
+}
+
+func (m *mmc2) CPUWrite(addr uint16, data byte) bool {
+	switch {
+	case addr >= 0xF000:
+		m.regs.Mirroring = data
+	case addr >= 0xB000:
+		m.regs.CHRBanks[(addr-0xB000)>>12] = data & 0x1F
+	case addr >= 0xA000:
+		m.regs.PRGBank = data
+	case addr >= 0x6000 && addr <= 0x7FFF:
+		return m.writePRGRAM(addr, data)
+	default:
+		return false
+	}
+	return true
+}
+
+func (m *mmc2) PPURead(addr uint16) (byte, bool) {
+	if addr > 0x1FFF {
+		return 0x00, false
+	}
+	data := m.readCHR(0x1000, m.chrBank(addr), addr)
+	m.watchLatch(addr)
+	return data, true
+}
+
+func (m *mmc2) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF {
+		return m.writeCHR(0x1000, m.chrBank(addr), addr, data)
+	}
+	return false
+}
+
+func (m *mmc2) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *mmc2) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
+
+// Private Methods
+
+func (m *mmc2) chrBank(addr uint16) int {
+	half := int(addr >> 12)
+	index := half * 2
+	if m.regs.Latches[half] == 0xFE {
+		index++
+	}
+	return int(m.regs.CHRBanks[index])
+}
+
+// watchLatch switches the latch of the pattern table half the PPU read
+// from on the $FD8/$FE8 tile fetch, the high bitplane of tile $FD or $FE.
+// The MMC2 first latch only reacts to the first row, $0FD8 and $0FE8.
+func (m *mmc2) watchLatch(addr uint16) {
+	half := addr >> 12
+	tile := addr & 0x0FF8
+	if half == 0 && !m.mmc4 && addr&0x0007 != 0 {
+		return
+	}
+	switch tile {
+	case 0x0FD8:
+		m.regs.Latches[half] = 0xFD
+	case 0x0FE8:
+		m.regs.Latches[half] = 0xFE
+	}
+}
@@ -0,0 +1,268 @@
+package mapper
+
+import (
+	"errors"
+	"io"
+)
+
+// MMC3 (mapper 4) switches PRG in 8KB and CHR in 1KB/2KB banks through
+// eight bank registers, and counts scanlines by watching PPU A12 rise as
+// the PPU moves from one pattern table to the other.
+//
+// Submapper 1 is the MMC6, with 1KB of internal PRG RAM at $7000 split in
+// two halves that are protected separately. Submapper 4 is the older
+// MMC3A behaviour, which only raises an IRQ when the counter is
+// decremented to zero and not when it is reloaded with zero.
+type mmc3 struct {
+	board
+	regs mmc3Registers
+
+	cycle       uint64
+	a12         bool
+	a12LowCycle uint64
+}
+
+type mmc3Registers struct {
+	BankSelect byte
+	Banks      [8]byte
+	Mirroring  byte
+	PRGRAM     byte
+
+	IRQLatch   byte
+	IRQCounter byte
+	IRQReload  bool
+	IRQEnable  bool
+	IRQActive  bool
+}
+
+const (
+	mmc3SubmapperMMC6  = 1
+	mmc3SubmapperMMC3A = 4
+
+	// A12 has to stay low this many CPU cycles before a rise counts. It
+	// filters out the short drops between pattern fetches, including the
+	// ten dots from the prefetch of the next line to its first pattern
+	// fetch when the background uses $1000.
+	mmc3A12Filter = 4
+)
+
+func init() {
+	Register(4, newMMC3)
+}
+
+func newMMC3(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("mmc3: missing PRG ROM")
+	}
+	m := &mmc3{board: newBoard(cfg)}
+	if m.submapper == mmc3SubmapperMMC6 {
+		m.prgRAM = make([]byte, 0x0400)
+		m.placeTrainer()
+	}
+	m.Reset()
+	return m, nil
+}
+
+func (m *mmc3) Reset() {
+	m.regs = mmc3Registers{
+		Banks: [8]byte{0, 2, 4, 5, 6, 7, 0, 1},
+	}
+}
+
+func (m *mmc3) Clock() {
+	m.cycle++
+}
+
+func (m *mmc3) IRQ() bool {
+	return m.regs.IRQActive
+}
+
+func (m *mmc3) Mirroring() Mirroring {
+	if m.mirroring == FourScreen {
+		return FourScreen
+	}
+	if m.regs.Mirroring&0x01 != 0 {
+		return Horizontal
+	}
+	return Vertical
+}
+
+func (m *mmc3) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	switch {
+	case addr >= 0x8000:
+		return m.readPRG(0x2000, m.prgBank(addr), addr), true
+	case addr >= 0x6000:
+		offset, readable, _ := m.prgRAMAccess(addr)
+		if !readable {
+			return 0x00, false
+		}
+		return m.prgRAM[offset], true
+	}
+	return 0x00, false
+}
+
+func (m *mmc3) CPUWrite(addr uint16, data byte) bool {
+	if addr >= 0x6000 && addr <= 0x7FFF {
+		offset, _, writable := m.prgRAMAccess(addr)
+		if !writable {
+			return false
+		}
+		m.prgRAM[offset] = data
+		return true
+	}
+	if addr < 0x8000 {
+		return false
+	}
+
+	even := addr&0x0001 == 0
+	switch {
+	case addr <= 0x9FFF && even:
+		m.regs.BankSelect = data
+	case addr <= 0x9FFF:
+		m.regs.Banks[m.regs.BankSelect&0x07] = data
+	case addr <= 0xBFFF && even:
+		m.regs.Mirroring = data
+	case addr <= 0xBFFF:
+		m.regs.PRGRAM = data
+	case addr <= 0xDFFF && even:
+		m.regs.IRQLatch = data
+	case addr <= 0xDFFF:
+		m.regs.IRQCounter = 0
+		m.regs.IRQReload = true
+	case even:
+		m.regs.IRQEnable = false
+		m.regs.IRQActive = false
+	default:
+		m.regs.IRQEnable = true
+	}
+	return true
+}
+
+func (m *mmc3) PPURead(addr uint16) (byte, bool) {
+	m.watchA12(addr)
+	if addr <= 0x1FFF {
+		return m.chr[m.chrOffset(addr)], true
+	}
+	return 0x00, false
+}
+
+func (m *mmc3) PPUWrite(addr uint16, data byte) bool {
+	m.watchA12(addr)
+	if addr <= 0x1FFF && m.chrRAM {
+		m.chr[m.chrOffset(addr)] = data
+		return true
+	}
+	return false
+}
+
+func (m *mmc3) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *mmc3) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
+
+// Private Methods
+
+// prgBank returns the 8KB bank mapped at addr. Bit 6 of bank select swaps
+// R6 at $8000 with the fixed second to last bank at $C000.
+func (m *mmc3) prgBank(addr uint16) int {
+	last := m.prgBanks(0x2000) - 1
+	swap := m.regs.BankSelect&0x40 != 0
+
+	switch (addr >> 13) & 0x03 {
+	case 0: // $8000
+		if swap {
+			return last - 1
+		}
+		return int(m.regs.Banks[6] & 0x3F)
+	case 1: // $A000
+		return int(m.regs.Banks[7] & 0x3F)
+	case 2: // $C000
+		if swap {
+			return int(m.regs.Banks[6] & 0x3F)
+		}
+		return last - 1
+	}
+	return last
+}
+
+// chrOffset resolves a pattern table address. R0 and R1 select 2KB banks
+// and R2-R5 1KB banks, bit 7 of bank select swaps the two halves.
+func (m *mmc3) chrOffset(addr uint16) int {
+	if m.regs.BankSelect&0x80 != 0 {
+		addr ^= 0x1000
+	}
+	switch {
+	case addr < 0x0800:
+		return m.board.chrOffset(0x0800, int(m.regs.Banks[0]>>1), addr)
+	case addr < 0x1000:
+		return m.board.chrOffset(0x0800, int(m.regs.Banks[1]>>1), addr)
+	}
+	bank := m.regs.Banks[2+(addr-0x1000)/0x0400]
+	return m.board.chrOffset(0x0400, int(bank), addr)
+}
+
+// prgRAMAccess resolves $6000-$7FFF and reports whether the current
+// protection allows reading and writing it
+func (m *mmc3) prgRAMAccess(addr uint16) (offset int, readable bool, writable bool) {
+	if len(m.prgRAM) == 0 {
+		return 0, false, false
+	}
+
+	if m.submapper == mmc3SubmapperMMC6 {
+		if addr < 0x7000 || m.regs.BankSelect&0x20 == 0 {
+			return 0, false, false
+		}
+		offset = int(addr & 0x03FF)
+		shift := 4
+		if offset >= 0x0200 {
+			shift = 6
+		}
+		readable = m.regs.PRGRAM>>(shift+1)&0x01 != 0
+		writable = readable && m.regs.PRGRAM>>shift&0x01 != 0
+		return offset, readable, writable
+	}
+
+	offset = int(addr-0x6000) % len(m.prgRAM)
+	readable = m.regs.PRGRAM&0x80 != 0
+	writable = readable && m.regs.PRGRAM&0x40 == 0
+	return offset, readable, writable
+}
+
+func (m *mmc3) watchA12(addr uint16) {
+	if addr&0x1000 == 0 {
+		if m.a12 {
+			m.a12LowCycle = m.cycle
+		}
+		m.a12 = false
+		return
+	}
+
+	if !m.a12 && m.cycle-m.a12LowCycle >= mmc3A12Filter {
+		m.clockIRQCounter()
+	}
+	m.a12 = true
+}
+
+func (m *mmc3) clockIRQCounter() {
+	// A counter that runs out on its own reloads without an IRQ on the
+	// MMC3A, a reload requested through $C001 still raises one
+	natural := m.regs.IRQCounter == 0 && !m.regs.IRQReload
+	if m.regs.IRQCounter == 0 || m.regs.IRQReload {
+		m.regs.IRQCounter = m.regs.IRQLatch
+		m.regs.IRQReload = false
+	} else {
+		m.regs.IRQCounter--
+		natural = false
+	}
+
+	if m.regs.IRQCounter != 0 || !m.regs.IRQEnable {
+		return
+	}
+	if m.submapper == mmc3SubmapperMMC3A && natural {
+		return
+	}
+	m.regs.IRQActive = true
+}
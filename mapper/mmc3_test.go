@@ -0,0 +1,85 @@
+package mapper_test
+
+import (
+	"testing"
+
+	"github.com/patrickn2/gonesemulator/mapper"
+	"github.com/patrickn2/gonesemulator/ppu"
+)
+
+type irqTime struct {
+	scanline int
+	dot      int
+}
+
+// mmc3IRQs runs an MMC3 behind a real PPU for one frame and lists when
+// every IRQ was raised. The handler acknowledges and re-enables the IRQ
+// the way games do, so the counter keeps reloading from the latch.
+func mmc3IRQs(t *testing.T, control byte, latch byte) []irqTime {
+	t.Helper()
+	m, err := mapper.New(4, mapper.Config{
+		PRG:       make([]byte, 0x8000),
+		CHR:       make([]byte, 0x2000),
+		Mirroring: mapper.Vertical,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := ppu.New()
+	p.ConnectCartridge(m)
+	// Hide every sprite so that only empty slots are fetched
+	for i := 0; i < 256; i++ {
+		p.CPUWrite(0x0004, 0xFF)
+	}
+	p.CPUWrite(0x0000, control)
+	p.CPUWrite(0x0001, 0x18)
+
+	m.CPUWrite(0xC000, latch)
+	m.CPUWrite(0xC001, 0x00)
+	m.CPUWrite(0xE001, 0x00)
+
+	var irqs []irqTime
+	// The first frame starts on the pre-render line and skips no dot
+	for dot := 0; dot < 341*262; dot += 3 {
+		m.Clock()
+		p.Clock()
+		p.Clock()
+		p.Clock()
+		if m.IRQ() {
+			irqs = append(irqs, irqTime{dot/341 - 1, dot % 341})
+			m.CPUWrite(0xE000, 0x00)
+			m.CPUWrite(0xE001, 0x00)
+		}
+	}
+	return irqs
+}
+
+func TestMMC3ScanlineIRQ(t *testing.T) {
+	tests := []struct {
+		name             string
+		control          byte
+		firstDot, endDot int
+	}{
+		// A12 rises with the first sprite pattern fetch at dot 261
+		{"sprites at $1000", 0x08, 256, 264},
+		{"8x16 sprites", 0x20, 256, 264},
+		// A12 rises with the first background prefetch at dot 325
+		{"background at $1000", 0x10, 318, 328},
+	}
+	for _, tt := range tests {
+		irqs := mmc3IRQs(t, tt.control, 10)
+		// The pre-render line clocks the reload, then every 11th line
+		// counts the latch down to zero
+		if len(irqs) != 21 {
+			t.Errorf("%s: %d IRQs in a frame, want 21: %v", tt.name, len(irqs), irqs)
+			continue
+		}
+		for i, irq := range irqs {
+			if irq.scanline != 9+11*i || irq.dot < tt.firstDot || irq.dot > tt.endDot {
+				t.Errorf("%s: IRQ %d on scanline %d dot %d, want scanline %d dot %d-%d",
+					tt.name, i, irq.scanline, irq.dot, 9+11*i, tt.firstDot, tt.endDot)
+				break
+			}
+		}
+	}
+}
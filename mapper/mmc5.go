@@ -0,0 +1,492 @@
+package mapper
+
+import (
+	"errors"
+	"io"
+)
+
+// MMC5 (mapper 5) is Nintendo's most capable mapper. Next to four PRG and
+// CHR banking modes it has 1KB of ExRAM usable as a third nametable, as
+// per tile attributes and CHR banks or as plain RAM, a fill mode
+// nametable, a vertical split region and a hardware multiplier.
+//
+// The chip has no PPU A12 or scanline input. It finds the start of a
+// scanline by watching the PPU read the same nametable address three
+// times in a row, and tells background from sprite fetches by counting
+// reads from there: 128 background fetches, 32 sprite fetches, then the
+// first two tiles of the next line. Writes to PPUCTRL and PPUMASK are
+// snooped for the sprite size and rendering state.
+type mmc5 struct {
+	board
+	regs mmc5Registers
+}
+
+type mmc5Registers struct {
+	PRGMode    byte
+	CHRMode    byte
+	PRGProtect [2]byte
+	ExRAMMode  byte
+	Nametables byte
+	FillTile   byte
+	FillColor  byte
+	PRGBanks   [5]byte // $5113-$5117
+	CHRBanks   [12]byte
+	CHRUpper   byte
+	CHRSetB    bool // last CHR bank write went to $5128-$512B
+
+	SplitControl byte
+	SplitScroll  byte
+	SplitBank    byte
+
+	IRQCompare byte
+	IRQEnable  bool
+	IRQPending bool
+
+	Multiplicand byte
+	Multiplier   byte
+
+	ExRAM [1024]byte
+
+	// Snooped PPU state
+	Sprites8x16 bool
+	Rendering   bool
+
+	// Scanline detection. Active is set while the PPU fetches with
+	// rendering enabled, InFrame once a scanline start was seen.
+	Active     bool
+	InFrame    bool
+	Scanline   byte
+	LastAddr   uint16
+	MatchCount byte
+	Fetch      uint16
+	IdleCycles byte
+
+	// State latched by the last background nametable fetch
+	Column    byte
+	SplitY    byte
+	SplitTile bool
+	ExAttr    byte
+}
+
+const (
+	mmc5BackgroundFetches = 128
+	mmc5SpriteFetches     = 32
+
+	// In-frame drops once the PPU stops reading for this many CPU cycles
+	mmc5IdleCycles = 3
+)
+
+func init() {
+	Register(5, newMMC5)
+}
+
+func newMMC5(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("mmc5: missing PRG ROM")
+	}
+	m := &mmc5{board: newBoard(cfg)}
+	m.Reset()
+	return m, nil
+}
+
+func (m *mmc5) Reset() {
+	exram := m.regs.ExRAM
+	m.regs = mmc5Registers{
+		PRGMode:      0x03,
+		CHRMode:      0x03,
+		Multiplicand: 0xFF,
+		Multiplier:   0xFF,
+		ExRAM:        exram,
+		IdleCycles:   mmc5IdleCycles,
+	}
+	m.regs.PRGBanks[4] = 0xFF
+}
+
+func (m *mmc5) Mirroring() Mirroring {
+	return Mirroring(m.regs.Nametables)
+}
+
+func (m *mmc5) IRQ() bool {
+	return m.regs.IRQPending && m.regs.IRQEnable
+}
+
+func (m *mmc5) Clock() {
+	if m.regs.IdleCycles < mmc5IdleCycles {
+		m.regs.IdleCycles++
+		if m.regs.IdleCycles == mmc5IdleCycles {
+			m.leaveFrame()
+		}
+	}
+}
+
+func (m *mmc5) SnoopPPU(reg uint16, data byte) {
+	switch reg {
+	case 0x0000:
+		m.regs.Sprites8x16 = data&0x20 != 0
+	case 0x0001:
+		m.regs.Rendering = data&0x18 != 0
+		if !m.regs.Rendering {
+			m.leaveFrame()
+		}
+	}
+}
+
+func (m *mmc5) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	switch {
+	case addr >= 0x6000:
+		offset, rom, ok := m.prgOffset(addr)
+		switch {
+		case !ok:
+			return 0x00, false
+		case rom:
+			return m.prg[offset], true
+		}
+		return m.prgRAM[offset], true
+	case addr >= 0x5C00:
+		if m.regs.ExRAMMode < 2 {
+			return 0x00, false
+		}
+		return m.regs.ExRAM[addr-0x5C00], true
+	case addr == 0x5204:
+		var status byte
+		if m.regs.IRQPending {
+			status |= 0x80
+		}
+		if m.regs.InFrame {
+			status |= 0x40
+		}
+		if !bReadOnly {
+			m.regs.IRQPending = false
+		}
+		return status, true
+	case addr == 0x5205:
+		return byte(m.product()), true
+	case addr == 0x5206:
+		return byte(m.product() >> 8), true
+	}
+	return 0x00, false
+}
+
+func (m *mmc5) CPUWrite(addr uint16, data byte) bool {
+	switch {
+	case addr >= 0x6000:
+		offset, rom, ok := m.prgOffset(addr)
+		if !ok || rom || !m.prgRAMWritable() {
+			return false
+		}
+		m.prgRAM[offset] = data
+		return true
+	case addr >= 0x5C00:
+		switch m.regs.ExRAMMode {
+		case 0, 1:
+			// Writes outside rendering store zero
+			if !m.regs.InFrame {
+				data = 0x00
+			}
+		case 3:
+			return false
+		}
+		m.regs.ExRAM[addr-0x5C00] = data
+		return true
+	case addr >= 0x5120 && addr <= 0x512B:
+		m.regs.CHRBanks[addr-0x5120] = data
+		m.regs.CHRSetB = addr >= 0x5128
+		return true
+	case addr >= 0x5113 && addr <= 0x5117:
+		m.regs.PRGBanks[addr-0x5113] = data
+		return true
+	}
+
+	switch addr {
+	case 0x5100:
+		m.regs.PRGMode = data & 0x03
+	case 0x5101:
+		m.regs.CHRMode = data & 0x03
+	case 0x5102, 0x5103:
+		m.regs.PRGProtect[addr-0x5102] = data & 0x03
+	case 0x5104:
+		m.regs.ExRAMMode = data & 0x03
+	case 0x5105:
+		m.regs.Nametables = data
+	case 0x5106:
+		m.regs.FillTile = data
+	case 0x5107:
+		m.regs.FillColor = data & 0x03
+	case 0x5130:
+		m.regs.CHRUpper = data & 0x03
+	case 0x5200:
+		m.regs.SplitControl = data
+	case 0x5201:
+		m.regs.SplitScroll = data
+	case 0x5202:
+		m.regs.SplitBank = data
+	case 0x5203:
+		m.regs.IRQCompare = data
+	case 0x5204:
+		m.regs.IRQEnable = data&0x80 != 0
+	case 0x5205:
+		m.regs.Multiplicand = data
+	case 0x5206:
+		m.regs.Multiplier = data
+	default:
+		return false
+	}
+	return true
+}
+
+func (m *mmc5) PPURead(addr uint16) (byte, bool) {
+	m.watchFetch(addr)
+
+	background := m.backgroundFetch()
+	switch {
+	case addr <= 0x1FFF:
+		if background && m.regs.SplitTile {
+			offset := int(m.regs.SplitBank)*0x1000 + int(addr&0x0FF8|uint16(m.regs.SplitY&0x07))
+			return m.chr[offset%len(m.chr)], true
+		}
+		if background && m.regs.ExRAMMode == 1 {
+			bank := int(m.regs.CHRUpper)<<6 | int(m.regs.ExAttr&0x3F)
+			return m.readCHR(0x1000, bank, addr), true
+		}
+		return m.chr[m.chrOffset(addr, background)], true
+	case addr <= 0x3EFF:
+		return m.readNametable(addr, background)
+	}
+	return 0x00, false
+}
+
+func (m *mmc5) PPUWrite(addr uint16, data byte) bool {
+	switch {
+	case addr <= 0x1FFF:
+		if !m.chrRAM {
+			return false
+		}
+		m.chr[m.chrOffset(addr, false)] = data
+		return true
+	case addr <= 0x3EFF:
+		switch m.Mirroring().Page(addr) {
+		case 2:
+			if m.regs.ExRAMMode < 2 {
+				m.regs.ExRAM[addr&0x03FF] = data
+			}
+			return true
+		case 3:
+			return true
+		}
+	}
+	return false
+}
+
+func (m *mmc5) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *mmc5) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
+
+// Private Methods
+
+func (m *mmc5) product() uint16 {
+	return uint16(m.regs.Multiplicand) * uint16(m.regs.Multiplier)
+}
+
+// prgOffset resolves $6000-$FFFF to ROM or RAM. $6000 is always RAM and
+// windows banked by $5117 always ROM, the others follow bit 7 of their
+// bank.
+func (m *mmc5) prgOffset(addr uint16) (offset int, rom bool, ok bool) {
+	if addr < 0x8000 {
+		offset, ok = m.prgRAMOffset(m.regs.PRGBanks[0], addr)
+		return offset, false, ok
+	}
+
+	var reg, size int
+	switch m.regs.PRGMode {
+	case 0:
+		reg, size = 4, 0x8000
+	case 1:
+		reg, size = 2+int(addr-0x8000)/0x4000*2, 0x4000
+	case 2:
+		reg, size = 2, 0x4000
+		if addr >= 0xC000 {
+			reg, size = 1+int(addr-0x8000)/0x2000, 0x2000
+		}
+	default:
+		reg, size = 1+int(addr-0x8000)/0x2000, 0x2000
+	}
+
+	// Bank numbers count 8KB pages whatever the window size
+	bank := m.regs.PRGBanks[reg] &^ byte(size/0x2000-1)
+	page := int(addr) % size / 0x2000
+	if reg == 4 {
+		bank |= 0x80
+	}
+	if bank&0x80 == 0 {
+		offset, ok = m.prgRAMOffset(bank+byte(page), addr)
+		return offset, false, ok
+	}
+	bank &= 0x7F
+	offset = ((int(bank)+page)*0x2000 + int(addr)%0x2000) % len(m.prg)
+	return offset, true, true
+}
+
+// prgRAMOffset maps an 8KB RAM bank onto the chips fitted. Bit 2 selects
+// the chip and bits 0-1 the bank inside a 32KB one, 8KB chips ignore them.
+func (m *mmc5) prgRAMOffset(bank byte, addr uint16) (int, bool) {
+	offset := int(addr) % 0x2000
+	switch len(m.prgRAM) {
+	case 0:
+		return 0, false
+	case 0x2000:
+		return offset, bank&0x04 == 0
+	case 0x4000:
+		return int(bank>>2&0x01)*0x2000 + offset, true
+	case 0x8000:
+		return int(bank&0x03)*0x2000 + offset, bank&0x04 == 0
+	}
+	return (int(bank&0x07)*0x2000 + offset) % len(m.prgRAM), true
+}
+
+func (m *mmc5) prgRAMWritable() bool {
+	return m.regs.PRGProtect[0] == 0x02 && m.regs.PRGProtect[1] == 0x01
+}
+
+// chrOffset resolves a pattern table address through bank set A or B.
+// With 8x16 sprites the PPU fetches sprites from set A and the background
+// from set B. Otherwise set A does everything during rendering, and
+// accesses through PPUDATA use the set written last.
+func (m *mmc5) chrOffset(addr uint16, background bool) int {
+	setB := m.regs.CHRSetB
+	if m.regs.Active {
+		setB = m.regs.Sprites8x16 && background
+	}
+
+	// Each window uses the last register of its group, $5127 in 8KB
+	// mode, $5123 and $5127 in 4KB mode and so on
+	size := 0x2000 >> m.regs.CHRMode
+	slot := int(addr) / size
+	perSlot := size / 0x0400
+	index := (slot+1)*perSlot - 1
+	if setB {
+		// Set B only has $5128-$512B, repeated in both halves
+		slots := max(0x1000/size, 1)
+		index = 8 + (slot%slots+1)*(4/slots) - 1
+	}
+
+	bank := int(m.regs.CHRUpper)<<8 | int(m.regs.CHRBanks[index])
+	return m.board.chrOffset(size, bank, addr)
+}
+
+// readNametable answers $2000-$3EFF. Split and extended attribute tiles
+// take over background fetches, the rest follows the $5105 mapping.
+func (m *mmc5) readNametable(addr uint16, background bool) (byte, bool) {
+	attribute := addr&0x03FF >= 0x03C0
+
+	if background && m.regs.SplitTile {
+		row, column := int(m.regs.SplitY/8), int(m.regs.Column-1)
+		if !attribute {
+			return m.regs.ExRAM[row*32+column], true
+		}
+		at := m.regs.ExRAM[0x03C0+row/4*8+column/4]
+		shift := (row&0x02)<<1 | column&0x02
+		return ((at >> shift) & 0x03) * 0x55, true
+	}
+	if background && attribute && m.regs.ExRAMMode == 1 {
+		return (m.regs.ExAttr >> 6) * 0x55, true
+	}
+
+	switch m.Mirroring().Page(addr) {
+	case 2:
+		if m.regs.ExRAMMode < 2 {
+			return m.regs.ExRAM[addr&0x03FF], true
+		}
+		return 0x00, true
+	case 3:
+		if attribute {
+			return m.regs.FillColor * 0x55, true
+		}
+		return m.regs.FillTile, true
+	}
+	return 0x00, false
+}
+
+// watchFetch follows the PPU read pattern to find scanlines, and latches
+// the split and extended attribute state on background nametable fetches
+func (m *mmc5) watchFetch(addr uint16) {
+	m.regs.Active = m.regs.Rendering && m.regs.IdleCycles < mmc5IdleCycles
+	m.regs.IdleCycles = 0
+	m.regs.Fetch++
+
+	nametable := addr >= 0x2000 && addr <= 0x3EFF
+	repeat := nametable && addr == m.regs.LastAddr
+	if repeat {
+		m.regs.MatchCount++
+	} else {
+		m.regs.MatchCount = 0
+	}
+	m.regs.LastAddr = addr
+
+	if m.regs.MatchCount == 2 && m.regs.Rendering {
+		m.startScanline()
+		return
+	}
+
+	if m.regs.Fetch == mmc5BackgroundFetches+mmc5SpriteFetches+1 {
+		m.startNextLine()
+	}
+
+	if !nametable || repeat || !m.backgroundFetch() || addr&0x03FF >= 0x03C0 {
+		return
+	}
+
+	column := m.regs.Column
+	m.regs.Column++ // readNametable sees the column after this fetch
+	m.regs.ExAttr = m.regs.ExRAM[addr&0x03FF]
+
+	threshold := m.regs.SplitControl & 0x1F
+	right := m.regs.SplitControl&0x40 != 0
+	m.regs.SplitTile = m.regs.SplitControl&0x80 != 0 && m.regs.ExRAMMode < 2 &&
+		column < 32 && (column < threshold) != right
+}
+
+// startScanline runs on the third identical nametable read
+func (m *mmc5) startScanline() {
+	m.regs.Fetch = 0
+	if !m.regs.InFrame {
+		m.regs.InFrame = true
+		m.regs.Scanline = 0
+		m.regs.IRQPending = false
+		return
+	}
+	m.regs.Scanline++
+	if m.regs.Scanline == m.regs.IRQCompare {
+		m.regs.IRQPending = true
+	}
+}
+
+// startNextLine runs once the sprite fetches are over, when the PPU moves
+// on to the first tiles of the following line
+func (m *mmc5) startNextLine() {
+	m.regs.Column = 0
+	line := 0
+	if m.regs.InFrame {
+		line = int(m.regs.Scanline) + 1
+	}
+	y := int(m.regs.SplitScroll) + line
+	if y >= 240 {
+		y -= 240
+	}
+	m.regs.SplitY = byte(y)
+}
+
+func (m *mmc5) leaveFrame() {
+	m.regs.InFrame = false
+	m.regs.Fetch = 0
+	m.regs.MatchCount = 0
+	m.regs.LastAddr = 0x0000
+}
+
+func (m *mmc5) backgroundFetch() bool {
+	f := m.regs.Fetch
+	return m.regs.Active && (f <= mmc5BackgroundFetches || f > mmc5BackgroundFetches+mmc5SpriteFetches)
+}
@@ -0,0 +1,262 @@
+package mapper
+
+import (
+	"errors"
+	"io"
+)
+
+// Mappers 225 and 228 latch their banking from the address of writes to
+// $8000-$FFFF, and both carry four 4-bit registers the menus use as RAM.
+// Mapper 226 has two data registers at $8000 and $8001 instead. All three
+// switch PRG in 16KB or 32KB units.
+
+// multicart225 decodes A14 as the outer bank, A13 mirroring, A12 the 16KB
+// PRG mode, A6-A11 the PRG bank and A0-A5 the 8KB CHR bank
+type multicart225 struct {
+	board
+	regs multicart225Registers
+}
+
+type multicart225Registers struct {
+	Latch uint16
+	RAM   [4]byte
+}
+
+// multicart226 selects 16KB PRG banks with bits 0-4 and 7 of $8000 and
+// bit 0 of $8001, bit 5 of $8000 being the 16KB mode and bit 6 mirroring
+type multicart226 struct {
+	board
+	regs multicart226Registers
+}
+
+type multicart226Registers struct {
+	Banks [2]byte
+}
+
+// multicart228 is the Active Enterprises board. A11-A12 select a 512KB PRG
+// chip, with chip 3 stored after chip 1 as there is no chip 2, A6-A10 the
+// 16KB bank in it, A5 the 16KB mode and A13 mirroring. The 8KB CHR bank is
+// A0-A3 above data bits 0-1.
+type multicart228 struct {
+	board
+	regs multicart228Registers
+}
+
+type multicart228Registers struct {
+	Latch uint16
+	Data  byte
+	RAM   [4]byte
+}
+
+func init() {
+	Register(225, newMulticart225)
+	Register(226, newMulticart226)
+	Register(228, newMulticart228)
+}
+
+func newMulticart225(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("multicart225: missing PRG ROM")
+	}
+	return &multicart225{board: newBoard(cfg)}, nil
+}
+
+func newMulticart226(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("multicart226: missing PRG ROM")
+	}
+	return &multicart226{board: newBoard(cfg)}, nil
+}
+
+func newMulticart228(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("multicart228: missing PRG ROM")
+	}
+	return &multicart228{board: newBoard(cfg)}, nil
+}
+
+func (m *multicart225) Reset() {
+	m.regs.Latch = 0
+}
+
+func (m *multicart225) Mirroring() Mirroring {
+	if m.regs.Latch&0x2000 != 0 {
+		return Horizontal
+	}
+	return Vertical
+}
+
+func (m *multicart225) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	switch {
+	case addr >= 0x8000:
+		bank := int(m.regs.Latch>>6&0x3F | m.regs.Latch>>8&0x40)
+		return readMulticartPRG(&m.board, bank, m.regs.Latch&0x1000 != 0, addr), true
+	case addr >= 0x5800 && addr <= 0x5FFF:
+		return m.regs.RAM[addr&0x03], true
+	}
+	return 0x00, false
+}
+
+func (m *multicart225) CPUWrite(addr uint16, data byte) bool {
+	switch {
+	case addr >= 0x8000:
+		m.regs.Latch = addr
+	case addr >= 0x5800 && addr <= 0x5FFF:
+		m.regs.RAM[addr&0x03] = data & 0x0F
+	default:
+		return false
+	}
+	return true
+}
+
+func (m *multicart225) PPURead(addr uint16) (byte, bool) {
+	if addr <= 0x1FFF {
+		return m.readCHR(0x2000, m.chrBank(), addr), true
+	}
+	return 0x00, false
+}
+
+func (m *multicart225) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF {
+		return m.writeCHR(0x2000, m.chrBank(), addr, data)
+	}
+	return false
+}
+
+func (m *multicart225) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *multicart225) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
+
+func (m *multicart226) Reset() {
+	m.regs = multicart226Registers{}
+}
+
+func (m *multicart226) Mirroring() Mirroring {
+	if m.regs.Banks[0]&0x40 != 0 {
+		return Vertical
+	}
+	return Horizontal
+}
+
+func (m *multicart226) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	if addr >= 0x8000 {
+		bank := int(m.regs.Banks[0]&0x1F | m.regs.Banks[0]>>2&0x20 | m.regs.Banks[1]<<6&0x40)
+		return readMulticartPRG(&m.board, bank, m.regs.Banks[0]&0x20 != 0, addr), true
+	}
+	return 0x00, false
+}
+
+func (m *multicart226) CPUWrite(addr uint16, data byte) bool {
+	if addr >= 0x8000 {
+		m.regs.Banks[addr&0x01] = data
+		return true
+	}
+	return false
+}
+
+func (m *multicart226) PPURead(addr uint16) (byte, bool) {
+	if addr <= 0x1FFF {
+		return m.readCHR(0x2000, 0, addr), true
+	}
+	return 0x00, false
+}
+
+func (m *multicart226) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF {
+		return m.writeCHR(0x2000, 0, addr, data)
+	}
+	return false
+}
+
+func (m *multicart226) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *multicart226) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
+
+func (m *multicart228) Reset() {
+	m.regs.Latch = 0
+	m.regs.Data = 0
+}
+
+func (m *multicart228) Mirroring() Mirroring {
+	if m.regs.Latch&0x2000 != 0 {
+		return Horizontal
+	}
+	return Vertical
+}
+
+func (m *multicart228) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	switch {
+	case addr >= 0x8000:
+		chip := int(m.regs.Latch >> 11 & 0x03)
+		if chip == 3 {
+			chip = 2
+		}
+		bank := chip<<5 | int(m.regs.Latch>>6&0x1F)
+		return readMulticartPRG(&m.board, bank, m.regs.Latch&0x0020 != 0, addr), true
+	case addr >= 0x4020 && addr <= 0x5FFF:
+		return m.regs.RAM[addr&0x03], true
+	}
+	return 0x00, false
+}
+
+func (m *multicart228) CPUWrite(addr uint16, data byte) bool {
+	switch {
+	case addr >= 0x8000:
+		m.regs.Latch = addr
+		m.regs.Data = data
+	case addr >= 0x4020 && addr <= 0x5FFF:
+		m.regs.RAM[addr&0x03] = data & 0x0F
+	default:
+		return false
+	}
+	return true
+}
+
+func (m *multicart228) PPURead(addr uint16) (byte, bool) {
+	if addr <= 0x1FFF {
+		return m.readCHR(0x2000, m.chrBank(), addr), true
+	}
+	return 0x00, false
+}
+
+func (m *multicart228) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF {
+		return m.writeCHR(0x2000, m.chrBank(), addr, data)
+	}
+	return false
+}
+
+func (m *multicart228) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *multicart228) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
+
+// Private Methods
+
+// readMulticartPRG reads $8000-$FFFF from a 16KB bank, mirrored into both
+// halves in 16KB mode and paired with its neighbour otherwise
+func readMulticartPRG(b *board, bank int, mode16 bool, addr uint16) byte {
+	if !mode16 {
+		bank = bank&^1 | int(addr>>14&0x01)
+	}
+	return b.readPRG(0x4000, bank, addr)
+}
+
+func (m *multicart225) chrBank() int {
+	return int(m.regs.Latch&0x3F | m.regs.Latch>>8&0x40)
+}
+
+func (m *multicart228) chrBank() int {
+	return int(m.regs.Latch&0x0F)<<2 | int(m.regs.Data&0x03)
+}
@@ -0,0 +1,219 @@
+package mapper
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Namco 129 and 163 (mapper 19) switch three 8KB PRG banks and twelve 1KB
+// CHR banks, eight for the pattern tables and four for the nametables.
+// Nametable banks $E0 and up select the console CIRAM page given by bit
+// 0, lower values put CHR ROM in the nametable. Pattern table banks of
+// $E0 and up would select CIRAM too, which is not emulated.
+//
+// The chip has 128 bytes of internal RAM reached through a data port at
+// $4800 and an address port at $F800 that can auto-increment, which games
+// also use to keep battery saves. A 15 bit counter at $5000/$5800 counts
+// CPU cycles up to $7FFF and raises an IRQ there. The 163 expansion audio
+// is not emulated.
+type namco163 struct {
+	board
+	regs namco163Registers
+
+	// nvram holds the PRG RAM followed by the internal RAM, the memory the
+	// battery keeps
+	nvram []byte
+	ram   []byte
+}
+
+type namco163Registers struct {
+	CHRBanks   [12]byte
+	PRGBanks   [3]byte
+	Protect    byte // $F800, which is also the RAM address port
+	IRQCounter uint16
+	IRQEnable  bool
+	IRQPending bool
+}
+
+func init() {
+	Register(19, newNamco163)
+}
+
+func newNamco163(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("namco163: missing PRG ROM")
+	}
+	m := &namco163{board: newBoard(cfg)}
+	m.nvram = make([]byte, len(m.prgRAM)+0x80)
+	m.prgRAM = m.nvram[:len(m.prgRAM)]
+	m.ram = m.nvram[len(m.prgRAM):]
+	m.placeTrainer()
+	m.Reset()
+	return m, nil
+}
+
+func (m *namco163) Reset() {
+	m.regs = namco163Registers{}
+	m.regs.CHRBanks[8] = 0xE0
+	m.regs.CHRBanks[9] = 0xE0
+	m.regs.CHRBanks[10] = 0xE1
+	m.regs.CHRBanks[11] = 0xE1
+}
+
+func (m *namco163) Clock() {
+	if m.regs.IRQEnable && m.regs.IRQCounter < 0x7FFF {
+		m.regs.IRQCounter++
+		if m.regs.IRQCounter == 0x7FFF {
+			m.regs.IRQPending = true
+		}
+	}
+}
+
+func (m *namco163) IRQ() bool {
+	return m.regs.IRQPending
+}
+
+// Mirroring reports the CIRAM pages of the nametables mapped to CIRAM,
+// the others are answered from CHR ROM by PPURead
+func (m *namco163) Mirroring() Mirroring {
+	var mirroring Mirroring
+	for i, bank := range m.regs.CHRBanks[8:] {
+		mirroring |= Mirroring(bank&0x01) << (i * 2)
+	}
+	return mirroring
+}
+
+// BatteryRAM is the PRG RAM followed by the internal RAM, games keep
+// saves in either
+func (m *namco163) BatteryRAM() []byte {
+	if !m.battery {
+		return nil
+	}
+	return m.nvram
+}
+
+func (m *namco163) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	switch {
+	case addr >= 0xE000:
+		return m.readPRG(0x2000, m.prgBanks(0x2000)-1, addr), true
+	case addr >= 0x8000:
+		bank := m.regs.PRGBanks[(addr-0x8000)/0x2000]
+		return m.readPRG(0x2000, int(bank&0x3F), addr), true
+	case addr >= 0x6000:
+		return m.readPRGRAM(addr)
+	case addr >= 0x5800:
+		high := byte(m.regs.IRQCounter >> 8)
+		if m.regs.IRQEnable {
+			high |= 0x80
+		}
+		return high, true
+	case addr >= 0x5000:
+		return byte(m.regs.IRQCounter), true
+	case addr >= 0x4800:
+		if bReadOnly {
+			return m.ram[m.regs.Protect&0x7F], true
+		}
+		return m.accessRAM(), true
+	}
+	return 0x00, false
+}
+
+func (m *namco163) CPUWrite(addr uint16, data byte) bool {
+	switch {
+	case addr >= 0xF800:
+		m.regs.Protect = data
+	case addr >= 0xF000:
+		m.regs.PRGBanks[2] = data
+	case addr >= 0xE800:
+		m.regs.PRGBanks[1] = data
+	case addr >= 0xE000:
+		m.regs.PRGBanks[0] = data
+	case addr >= 0x8000:
+		m.regs.CHRBanks[(addr-0x8000)/0x0800] = data
+	case addr >= 0x6000:
+		if !m.prgRAMWritable(addr) {
+			return false
+		}
+		return m.writePRGRAM(addr, data)
+	case addr >= 0x5800:
+		m.regs.IRQCounter = m.regs.IRQCounter&0x00FF | uint16(data&0x7F)<<8
+		m.regs.IRQEnable = data&0x80 != 0
+		m.regs.IRQPending = false
+	case addr >= 0x5000:
+		m.regs.IRQCounter = m.regs.IRQCounter&0x7F00 | uint16(data)
+		m.regs.IRQPending = false
+	case addr >= 0x4800:
+		m.ram[m.regs.Protect&0x7F] = data
+		m.accessRAM()
+	default:
+		return false
+	}
+	return true
+}
+
+func (m *namco163) PPURead(addr uint16) (byte, bool) {
+	switch {
+	case addr <= 0x1FFF:
+		return m.readCHR(0x0400, int(m.regs.CHRBanks[addr/0x0400]), addr), true
+	case addr <= 0x3EFF:
+		bank := m.regs.CHRBanks[8+(addr>>10)&0x03]
+		if bank >= 0xE0 {
+			return 0x00, false
+		}
+		return m.readCHR(0x0400, int(bank), addr), true
+	}
+	return 0x00, false
+}
+
+func (m *namco163) PPUWrite(addr uint16, data byte) bool {
+	switch {
+	case addr <= 0x1FFF:
+		return m.writeCHR(0x0400, int(m.regs.CHRBanks[addr/0x0400]), addr, data)
+	case addr <= 0x3EFF:
+		bank := m.regs.CHRBanks[8+(addr>>10)&0x03]
+		if bank >= 0xE0 {
+			return false
+		}
+		// CHR ROM nametables ignore writes
+		m.writeCHR(0x0400, int(bank), addr, data)
+		return true
+	}
+	return false
+}
+
+func (m *namco163) SaveState(w io.Writer) error {
+	if err := m.save(w, &m.regs); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, m.ram)
+}
+
+func (m *namco163) LoadState(r io.Reader) error {
+	if err := m.load(r, &m.regs); err != nil {
+		return err
+	}
+	return binary.Read(r, binary.LittleEndian, m.ram)
+}
+
+// Private Methods
+
+// accessRAM reads the internal RAM at the address port and moves it on
+// when auto-increment is set
+func (m *namco163) accessRAM() byte {
+	data := m.ram[m.regs.Protect&0x7F]
+	if m.regs.Protect&0x80 != 0 {
+		m.regs.Protect = 0x80 | (m.regs.Protect+1)&0x7F
+	}
+	return data
+}
+
+// prgRAMWritable checks $F800: the upper nibble must be $4 and bits 0-3
+// protect the four 2KB windows of $6000-$7FFF
+func (m *namco163) prgRAMWritable(addr uint16) bool {
+	if m.regs.Protect&0xF0 != 0x40 {
+		return false
+	}
+	window := (addr - 0x6000) / 0x0800
+	return m.regs.Protect>>window&0x01 == 0
+}
@@ -0,0 +1,36 @@
+package mapper
+
+import "testing"
+
+func TestNamco163BatteryRAM(t *testing.T) {
+	for _, ramSize := range []int{0, 0x2000} {
+		cfg := testConfig(0, 0x80000, 0x40000)
+		cfg.PRGRAMSize = ramSize
+		m, _ := New(19, cfg)
+
+		// Unprotect $6000 and fill internal RAM from $10 with auto-increment
+		m.CPUWrite(0xF800, 0x40)
+		m.CPUWrite(0x6000, 0x11)
+		m.CPUWrite(0xF800, 0x90)
+		m.CPUWrite(0x4800, 0x22)
+		m.CPUWrite(0x4800, 0x33)
+
+		ram := m.BatteryRAM()
+		if len(ram) != ramSize+0x80 {
+			t.Fatalf("%d bytes of PRG RAM: battery RAM is %d bytes, want %d", ramSize, len(ram), ramSize+0x80)
+		}
+		if ramSize > 0 && ram[0] != 0x11 {
+			t.Errorf("%d bytes of PRG RAM: PRG RAM not in battery RAM", ramSize)
+		}
+		if ram[ramSize+0x10] != 0x22 || ram[ramSize+0x11] != 0x33 {
+			t.Errorf("%d bytes of PRG RAM: internal RAM not in battery RAM", ramSize)
+		}
+
+		// A restored save shows up behind the data port
+		ram[ramSize+0x7F] = 0x44
+		m.CPUWrite(0xF800, 0x7F)
+		if got := cpuRead(m, 0x4800); got != 0x44 {
+			t.Errorf("%d bytes of PRG RAM: internal RAM $7F is $%02X, want $44", ramSize, got)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package mapper
+
+import (
+	"errors"
+	"io"
+)
+
+// NINA-03 and NINA-06 (mapper 79) switch 32KB of PRG with bit 3 and 8KB of
+// CHR with bits 0-2 of a register decoded at $4100-$5FFF wherever A8 is
+// set
+type nina0306 struct {
+	board
+	regs nina0306Registers
+}
+
+type nina0306Registers struct {
+	Bank byte
+}
+
+func init() {
+	Register(79, newNINA0306)
+}
+
+func newNINA0306(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("nina0306: missing PRG ROM")
+	}
+	return &nina0306{board: newBoard(cfg)}, nil
+}
+
+func (m *nina0306) Reset() {
+	m.regs = nina0306Registers{}
+}
+
+func (m *nina0306) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	if addr >= 0x8000 {
+		return m.readPRG(0x8000, int(m.regs.Bank>>3&0x01), addr), true
+	}
+	return 0x00, false
+}
+
+func (m *nina0306) CPUWrite(addr uint16, data byte) bool {
+	if addr&0xE100 == 0x4100 {
+		m.regs.Bank = data
+		return true
+	}
+	return false
+}
+
+func (m *nina0306) PPURead(addr uint16) (byte, bool) {
+	if addr <= 0x1FFF {
+		return m.readCHR(0x2000, int(m.regs.Bank&0x07), addr), true
+	}
+	return 0x00, false
+}
+
+func (m *nina0306) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF {
+		return m.writeCHR(0x2000, int(m.regs.Bank&0x07), addr, data)
+	}
+	return false
+}
+
+func (m *nina0306) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *nina0306) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
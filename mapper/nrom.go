@@ -0,0 +1,63 @@
+package mapper
+
+import (
+	"errors"
+	"io"
+)
+
+// NROM (mapper 0) has no banking at all. NROM-128 carries 16KB of PRG
+// mirrored into $C000, NROM-256 fills $8000-$FFFF with 32KB. Family BASIC
+// adds 8KB of PRG RAM at $6000.
+type nrom struct {
+	board
+}
+
+func init() {
+	Register(0, newNROM)
+}
+
+func newNROM(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("nrom: missing PRG ROM")
+	}
+	return &nrom{board: newBoard(cfg)}, nil
+}
+
+func (m *nrom) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	switch {
+	case addr >= 0x8000:
+		return m.prg[int(addr-0x8000)%len(m.prg)], true
+	case addr >= 0x6000:
+		return m.readPRGRAM(addr)
+	}
+	return 0x00, false
+}
+
+func (m *nrom) CPUWrite(addr uint16, data byte) bool {
+	if addr >= 0x6000 && addr <= 0x7FFF {
+		return m.writePRGRAM(addr, data)
+	}
+	return false
+}
+
+func (m *nrom) PPURead(addr uint16) (byte, bool) {
+	if addr <= 0x1FFF {
+		return m.readCHR(0x2000, 0, addr), true
+	}
+	return 0x00, false
+}
+
+func (m *nrom) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF {
+		return m.writeCHR(0x2000, 0, addr, data)
+	}
+	return false
+}
+
+func (m *nrom) SaveState(w io.Writer) error {
+	return m.save(w, nil)
+}
+
+func (m *nrom) LoadState(r io.Reader) error {
+	return m.load(r, nil)
+}
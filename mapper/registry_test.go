@@ -0,0 +1,53 @@
+package mapper
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNewUnsupported(t *testing.T) {
+	_, err := New(0xFFFF, testConfig(0, 0x8000, 0x2000))
+	if !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("got %v, want ErrUnsupported", err)
+	}
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) || unsupported.ID != 0xFFFF {
+		t.Errorf("got %#v, want an UnsupportedError for mapper 65535", err)
+	}
+}
+
+func TestSupported(t *testing.T) {
+	ids := Supported()
+	if !slices.IsSorted(ids) {
+		t.Errorf("%v is not sorted", ids)
+	}
+	for _, id := range []uint16{0, 1, 2, 3, 4, 7} {
+		if !slices.Contains(ids, id) {
+			t.Errorf("mapper %d missing from %v", id, ids)
+		}
+		if _, err := New(id, testConfig(0, 0x8000, 0x2000)); err != nil {
+			t.Errorf("mapper %d: %v", id, err)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	const id = 0xFFFE
+	t.Cleanup(func() { delete(constructors, id) })
+
+	Register(id, newNROM)
+	if !slices.Contains(Supported(), id) {
+		t.Errorf("mapper %d not listed after Register", id)
+	}
+	if _, err := New(id, testConfig(0, 0x8000, 0x2000)); err != nil {
+		t.Errorf("mapper %d: %v", id, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering mapper %d twice did not panic", id)
+		}
+	}()
+	Register(id, newNROM)
+}
@@ -0,0 +1,90 @@
+package mapper
+
+import (
+	"errors"
+	"io"
+)
+
+// UxROM (mapper 2) switches a 16KB PRG bank at $8000 and fixes the last
+// one at $C000, CHR is normally 8KB of RAM. UNROM uses 3 bits of the
+// register and UOROM 4, the bank is wrapped to the ROM size either way.
+//
+// UN1ROM (mapper 94) is the same board with the bank taken from bits 2-4.
+type uxrom struct {
+	board
+	regs  uxromRegisters
+	shift uint8
+}
+
+type uxromRegisters struct {
+	Bank byte
+}
+
+func init() {
+	Register(2, newUxROM)
+	Register(94, newUN1ROM)
+}
+
+func newUxROM(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("uxrom: missing PRG ROM")
+	}
+	return &uxrom{board: newBoard(cfg)}, nil
+}
+
+func newUN1ROM(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("un1rom: missing PRG ROM")
+	}
+	return &uxrom{board: newBoard(cfg), shift: 2}, nil
+}
+
+func (m *uxrom) Reset() {
+	m.regs = uxromRegisters{}
+}
+
+func (m *uxrom) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	switch {
+	case addr >= 0xC000:
+		return m.readPRG(0x4000, m.prgBanks(0x4000)-1, addr), true
+	case addr >= 0x8000:
+		return m.readPRG(0x4000, int(m.regs.Bank>>m.shift), addr), true
+	case addr >= 0x6000:
+		return m.readPRGRAM(addr)
+	}
+	return 0x00, false
+}
+
+func (m *uxrom) CPUWrite(addr uint16, data byte) bool {
+	switch {
+	case addr >= 0x8000:
+		rom, _ := m.CPURead(addr, true)
+		m.regs.Bank = m.busConflict(data, rom)
+		return true
+	case addr >= 0x6000:
+		return m.writePRGRAM(addr, data)
+	}
+	return false
+}
+
+func (m *uxrom) PPURead(addr uint16) (byte, bool) {
+	if addr <= 0x1FFF {
+		return m.readCHR(0x2000, 0, addr), true
+	}
+	return 0x00, false
+}
+
+func (m *uxrom) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF {
+		return m.writeCHR(0x2000, 0, addr, data)
+	}
+	return false
+}
+
+func (m *uxrom) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *uxrom) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
@@ -0,0 +1,292 @@
+package mapper
+
+import (
+	"errors"
+	"io"
+)
+
+// Konami VRC2 and VRC4 (mappers 21, 22, 23 and 25) switch two 8KB PRG
+// banks and eight 1KB CHR banks, each CHR bank written a nibble at a
+// time. VRC4 adds a PRG swap mode, single screen mirroring and the VRC IRQ
+// counter.
+//
+// Boards connect the two register select pins to different CPU address
+// lines, which is what tells the mapper numbers apart. NES 2.0 submappers
+// pick one wiring, submapper 0 accepts every wiring used with the mapper
+// number:
+//
+//	21  VRC4a A1 A2 (1), VRC4c A6 A7 (2)
+//	22  VRC2a A1 A0, CHR banks in 2KB units
+//	23  VRC4f A0 A1 (1), VRC4e A2 A3 (2), VRC2b A0 A1 (3)
+//	25  VRC4b A1 A0 (1), VRC4d A3 A2 (2), VRC2c A1 A0 (3)
+type vrc4 struct {
+	board
+	vrc2     bool
+	chrShift uint8
+	a0, a1   uint16
+	regs     vrc4Registers
+}
+
+type vrc4Registers struct {
+	PRGBanks  [2]byte
+	PRGMode   byte
+	Mirroring byte
+	CHRBanks  [8]uint16
+	Latch     byte
+	IRQ       vrcIRQ
+}
+
+const vrcSubmapperVRC2 = 3
+
+func init() {
+	for _, id := range []uint16{21, 22, 23, 25} {
+		Register(id, vrc4Constructor(id))
+	}
+}
+
+func vrc4Constructor(id uint16) Constructor {
+	return func(cfg Config) (Mapper, error) {
+		if len(cfg.PRG) == 0 {
+			return nil, errors.New("vrc: missing PRG ROM")
+		}
+		m := &vrc4{board: newBoard(cfg)}
+		switch id {
+		case 21:
+			m.a0, m.a1 = vrcLines(cfg.Submapper, 0x02, 0x04, 0x40, 0x80)
+		case 22:
+			m.a0, m.a1 = 0x02, 0x01
+			m.vrc2 = true
+			m.chrShift = 1
+		case 23:
+			m.a0, m.a1 = vrcLines(cfg.Submapper, 0x01, 0x02, 0x04, 0x08)
+			m.vrc2 = cfg.Submapper == vrcSubmapperVRC2
+		case 25:
+			m.a0, m.a1 = vrcLines(cfg.Submapper, 0x02, 0x01, 0x08, 0x04)
+			m.vrc2 = cfg.Submapper == vrcSubmapperVRC2
+		}
+		m.Reset()
+		return m, nil
+	}
+}
+
+// vrcLines picks the register select lines of the first wiring for
+// submappers 1 and 3, the second for 2 and both for 0
+func vrcLines(submapper uint8, a0, a1, b0, b1 uint16) (uint16, uint16) {
+	switch submapper {
+	case 1, vrcSubmapperVRC2:
+		return a0, a1
+	case 2:
+		return b0, b1
+	}
+	return a0 | b0, a1 | b1
+}
+
+func (m *vrc4) Reset() {
+	m.regs = vrc4Registers{}
+}
+
+func (m *vrc4) Clock() {
+	if !m.vrc2 {
+		m.regs.IRQ.clock()
+	}
+}
+
+func (m *vrc4) IRQ() bool {
+	return m.regs.IRQ.Pending
+}
+
+func (m *vrc4) Mirroring() Mirroring {
+	if m.vrc2 {
+		return vrcMirroring(m.regs.Mirroring & 0x01)
+	}
+	return vrcMirroring(m.regs.Mirroring)
+}
+
+func (m *vrc4) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	switch {
+	case addr >= 0x8000:
+		return m.readPRG(0x2000, m.prgBank(addr), addr), true
+	case addr >= 0x6000:
+		if len(m.prgRAM) == 0 && m.vrc2 && addr < 0x7000 {
+			return m.regs.Latch, true
+		}
+		return m.readPRGRAM(addr)
+	}
+	return 0x00, false
+}
+
+func (m *vrc4) CPUWrite(addr uint16, data byte) bool {
+	if addr >= 0x6000 && addr <= 0x7FFF {
+		// VRC2 boards without PRG RAM have a single bit latch instead
+		if len(m.prgRAM) == 0 && m.vrc2 && addr < 0x7000 {
+			m.regs.Latch = data & 0x01
+			return true
+		}
+		return m.writePRGRAM(addr, data)
+	}
+	if addr < 0x8000 {
+		return false
+	}
+
+	reg := vrcRegister(addr, m.a0, m.a1)
+	switch reg & 0xF000 {
+	case 0x8000:
+		m.regs.PRGBanks[0] = data & 0x1F
+	case 0x9000:
+		if m.vrc2 || reg&0x02 == 0 {
+			m.regs.Mirroring = data & 0x03
+		} else {
+			m.regs.PRGMode = data
+		}
+	case 0xA000:
+		m.regs.PRGBanks[1] = data & 0x1F
+	case 0xB000, 0xC000, 0xD000, 0xE000:
+		bank := &m.regs.CHRBanks[int(reg>>12-0xB)*2+int(reg>>1&0x01)]
+		if reg&0x01 == 0 {
+			*bank = *bank&0x01F0 | uint16(data&0x0F)
+		} else {
+			*bank = *bank&0x000F | uint16(data&0x1F)<<4
+		}
+	case 0xF000:
+		if m.vrc2 {
+			return true
+		}
+		switch reg & 0x03 {
+		case 0:
+			m.regs.IRQ.Latch = m.regs.IRQ.Latch&0xF0 | data&0x0F
+		case 1:
+			m.regs.IRQ.Latch = m.regs.IRQ.Latch&0x0F | data<<4
+		case 2:
+			m.regs.IRQ.control(data)
+		case 3:
+			m.regs.IRQ.acknowledge()
+		}
+	}
+	return true
+}
+
+func (m *vrc4) PPURead(addr uint16) (byte, bool) {
+	if addr <= 0x1FFF {
+		return m.readCHR(0x0400, m.chrBank(addr), addr), true
+	}
+	return 0x00, false
+}
+
+func (m *vrc4) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF {
+		return m.writeCHR(0x0400, m.chrBank(addr), addr, data)
+	}
+	return false
+}
+
+func (m *vrc4) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *vrc4) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
+
+// Private Methods
+
+// prgBank returns the 8KB bank at addr. In VRC4 swap mode the fixed
+// second to last bank moves from $C000 to $8000.
+func (m *vrc4) prgBank(addr uint16) int {
+	secondLast := m.prgBanks(0x2000) - 2
+	swap := !m.vrc2 && m.regs.PRGMode&0x02 != 0
+
+	switch (addr >> 13) & 0x03 {
+	case 0: // $8000
+		if swap {
+			return secondLast
+		}
+		return int(m.regs.PRGBanks[0])
+	case 1: // $A000
+		return int(m.regs.PRGBanks[1])
+	case 2: // $C000
+		if swap {
+			return int(m.regs.PRGBanks[0])
+		}
+		return secondLast
+	}
+	return secondLast + 1
+}
+
+func (m *vrc4) chrBank(addr uint16) int {
+	return int(m.regs.CHRBanks[addr/0x0400] >> m.chrShift)
+}
+
+// vrcRegister folds the board wiring back into the register number the
+// chip sees, $x000-$x003
+func vrcRegister(addr uint16, a0 uint16, a1 uint16) uint16 {
+	reg := addr & 0xF000
+	if addr&a0 != 0 {
+		reg |= 0x01
+	}
+	if addr&a1 != 0 {
+		reg |= 0x02
+	}
+	return reg
+}
+
+// vrcMirroring decodes the two bit mirroring field shared by the VRC chips
+func vrcMirroring(data byte) Mirroring {
+	switch data & 0x03 {
+	case 0:
+		return Vertical
+	case 1:
+		return Horizontal
+	case 2:
+		return SingleScreenLow
+	}
+	return SingleScreenHigh
+}
+
+// vrcIRQ is the IRQ counter of VRC4, VRC6 and VRC7. It counts up to $FF
+// and reloads from the latch, either every CPU cycle or once per scanline
+// through a prescaler dividing the CPU clock by 113.67.
+type vrcIRQ struct {
+	Latch          byte
+	Counter        byte
+	Prescaler      int16
+	Enabled        bool
+	EnableAfterAck bool
+	CycleMode      bool
+	Pending        bool
+}
+
+func (irq *vrcIRQ) control(data byte) {
+	irq.EnableAfterAck = data&0x01 != 0
+	irq.Enabled = data&0x02 != 0
+	irq.CycleMode = data&0x04 != 0
+	irq.Pending = false
+	if irq.Enabled {
+		irq.Counter = irq.Latch
+		irq.Prescaler = 341
+	}
+}
+
+func (irq *vrcIRQ) acknowledge() {
+	irq.Pending = false
+	irq.Enabled = irq.EnableAfterAck
+}
+
+// clock runs once per CPU cycle, the prescaler counts PPU dots
+func (irq *vrcIRQ) clock() {
+	if !irq.Enabled {
+		return
+	}
+	if !irq.CycleMode {
+		irq.Prescaler -= 3
+		if irq.Prescaler > 0 {
+			return
+		}
+		irq.Prescaler += 341
+	}
+	if irq.Counter == 0xFF {
+		irq.Counter = irq.Latch
+		irq.Pending = true
+		return
+	}
+	irq.Counter++
+}
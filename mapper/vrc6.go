@@ -0,0 +1,135 @@
+package mapper
+
+import (
+	"errors"
+	"io"
+)
+
+// Konami VRC6 (mappers 24 and 26) switches 16KB of PRG at $8000 and 8KB
+// at $C000, and has eight 1KB CHR banks and the VRC IRQ counter. VRC6b
+// (mapper 26) swaps the A0 and A1 register select lines.
+//
+// $B003 also selects PPU banking modes with CHR ROM nametables, which no
+// released game uses. Only the usual mode is implemented, 1KB CHR banks
+// with the mirroring in bits 2-3 and the PRG RAM enable in bit 7.
+// Expansion audio is not emulated.
+type vrc6 struct {
+	board
+	a0, a1 uint16
+	regs   vrc6Registers
+}
+
+type vrc6Registers struct {
+	PRGBank16 byte
+	PRGBank8  byte
+	Control   byte
+	CHRBanks  [8]byte
+	IRQ       vrcIRQ
+}
+
+func init() {
+	Register(24, vrc6Constructor(0x01, 0x02))
+	Register(26, vrc6Constructor(0x02, 0x01))
+}
+
+func vrc6Constructor(a0, a1 uint16) Constructor {
+	return func(cfg Config) (Mapper, error) {
+		if len(cfg.PRG) == 0 {
+			return nil, errors.New("vrc6: missing PRG ROM")
+		}
+		return &vrc6{board: newBoard(cfg), a0: a0, a1: a1}, nil
+	}
+}
+
+func (m *vrc6) Reset() {
+	m.regs = vrc6Registers{}
+}
+
+func (m *vrc6) Clock() {
+	m.regs.IRQ.clock()
+}
+
+func (m *vrc6) IRQ() bool {
+	return m.regs.IRQ.Pending
+}
+
+func (m *vrc6) Mirroring() Mirroring {
+	return vrcMirroring(m.regs.Control >> 2)
+}
+
+func (m *vrc6) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	switch {
+	case addr >= 0xE000:
+		return m.readPRG(0x2000, m.prgBanks(0x2000)-1, addr), true
+	case addr >= 0xC000:
+		return m.readPRG(0x2000, int(m.regs.PRGBank8), addr), true
+	case addr >= 0x8000:
+		return m.readPRG(0x4000, int(m.regs.PRGBank16), addr), true
+	case addr >= 0x6000:
+		if m.regs.Control&0x80 == 0 {
+			return 0x00, false
+		}
+		return m.readPRGRAM(addr)
+	}
+	return 0x00, false
+}
+
+func (m *vrc6) CPUWrite(addr uint16, data byte) bool {
+	if addr >= 0x6000 && addr <= 0x7FFF {
+		if m.regs.Control&0x80 == 0 {
+			return false
+		}
+		return m.writePRGRAM(addr, data)
+	}
+	if addr < 0x8000 {
+		return false
+	}
+
+	reg := vrcRegister(addr, m.a0, m.a1)
+	switch reg & 0xF000 {
+	case 0x8000:
+		m.regs.PRGBank16 = data & 0x0F
+	case 0xB000:
+		if reg == 0xB003 {
+			m.regs.Control = data
+		}
+	case 0xC000:
+		m.regs.PRGBank8 = data & 0x1F
+	case 0xD000:
+		m.regs.CHRBanks[reg&0x03] = data
+	case 0xE000:
+		m.regs.CHRBanks[4+reg&0x03] = data
+	case 0xF000:
+		switch reg & 0x03 {
+		case 0:
+			m.regs.IRQ.Latch = data
+		case 1:
+			m.regs.IRQ.control(data)
+		case 2:
+			m.regs.IRQ.acknowledge()
+		}
+	}
+	return true
+}
+
+func (m *vrc6) PPURead(addr uint16) (byte, bool) {
+	if addr <= 0x1FFF {
+		return m.readCHR(0x0400, int(m.regs.CHRBanks[addr/0x0400]), addr), true
+	}
+	return 0x00, false
+}
+
+func (m *vrc6) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF {
+		return m.writeCHR(0x0400, int(m.regs.CHRBanks[addr/0x0400]), addr, data)
+	}
+	return false
+}
+
+func (m *vrc6) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *vrc6) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
@@ -0,0 +1,142 @@
+package mapper
+
+import (
+	"errors"
+	"io"
+)
+
+// Konami VRC7 (mapper 85) switches three 8KB PRG banks and eight 1KB CHR
+// banks and has the VRC IRQ counter. Registers come in pairs told apart
+// by A4 on VRC7a (submapper 2) and A3 on VRC7b (submapper 1), submapper 0
+// accepts both. The FM expansion audio is not emulated.
+type vrc7 struct {
+	board
+	line uint16
+	regs vrc7Registers
+}
+
+type vrc7Registers struct {
+	PRGBanks [3]byte
+	CHRBanks [8]byte
+	Control  byte
+	IRQ      vrcIRQ
+}
+
+func init() {
+	Register(85, newVRC7)
+}
+
+func newVRC7(cfg Config) (Mapper, error) {
+	if len(cfg.PRG) == 0 {
+		return nil, errors.New("vrc7: missing PRG ROM")
+	}
+	m := &vrc7{board: newBoard(cfg)}
+	switch cfg.Submapper {
+	case 1:
+		m.line = 0x08
+	case 2:
+		m.line = 0x10
+	default:
+		m.line = 0x18
+	}
+	return m, nil
+}
+
+func (m *vrc7) Reset() {
+	m.regs = vrc7Registers{}
+}
+
+func (m *vrc7) Clock() {
+	m.regs.IRQ.clock()
+}
+
+func (m *vrc7) IRQ() bool {
+	return m.regs.IRQ.Pending
+}
+
+func (m *vrc7) Mirroring() Mirroring {
+	return vrcMirroring(m.regs.Control)
+}
+
+func (m *vrc7) CPURead(addr uint16, bReadOnly bool) (byte, bool) {
+	switch {
+	case addr >= 0xE000:
+		return m.readPRG(0x2000, m.prgBanks(0x2000)-1, addr), true
+	case addr >= 0x8000:
+		return m.readPRG(0x2000, int(m.regs.PRGBanks[(addr-0x8000)/0x2000]), addr), true
+	case addr >= 0x6000:
+		if m.regs.Control&0x80 == 0 {
+			return 0x00, false
+		}
+		return m.readPRGRAM(addr)
+	}
+	return 0x00, false
+}
+
+func (m *vrc7) CPUWrite(addr uint16, data byte) bool {
+	if addr >= 0x6000 && addr <= 0x7FFF {
+		if m.regs.Control&0x80 == 0 {
+			return false
+		}
+		return m.writePRGRAM(addr, data)
+	}
+	if addr < 0x8000 {
+		return false
+	}
+
+	second := addr&m.line != 0
+	switch addr & 0xF000 {
+	case 0x8000:
+		if second {
+			m.regs.PRGBanks[1] = data & 0x3F
+		} else {
+			m.regs.PRGBanks[0] = data & 0x3F
+		}
+	case 0x9000:
+		// $9010 and $9030 are the audio ports
+		if !second && addr&0x0030 == 0 {
+			m.regs.PRGBanks[2] = data & 0x3F
+		}
+	case 0xA000, 0xB000, 0xC000, 0xD000:
+		index := int(addr>>12-0xA) * 2
+		if second {
+			index++
+		}
+		m.regs.CHRBanks[index] = data
+	case 0xE000:
+		if second {
+			m.regs.IRQ.Latch = data
+		} else {
+			m.regs.Control = data
+		}
+	case 0xF000:
+		if second {
+			m.regs.IRQ.acknowledge()
+		} else {
+			m.regs.IRQ.control(data)
+		}
+	}
+	return true
+}
+
+func (m *vrc7) PPURead(addr uint16) (byte, bool) {
+	if addr <= 0x1FFF {
+		return m.readCHR(0x0400, int(m.regs.CHRBanks[addr/0x0400]), addr), true
+	}
+	return 0x00, false
+}
+
+func (m *vrc7) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF {
+		return m.writeCHR(0x0400, int(m.regs.CHRBanks[addr/0x0400]), addr, data)
+	}
+	return false
+}
+
+func (m *vrc7) SaveState(w io.Writer) error {
+	return m.save(w, &m.regs)
+}
+
+func (m *vrc7) LoadState(r io.Reader) error {
+	return m.load(r, &m.regs)
+}
@@ -0,0 +1,327 @@
+package mapper
+
+import (
+	"fmt"
+	"testing"
+)
+
+// bankedConfig fills every 8KB PRG bank and every 1KB CHR bank with its
+// own number
+func bankedConfig(submapper uint8) Config {
+	prg := make([]byte, 0x80000)
+	for i := range prg {
+		prg[i] = byte(i / 0x2000)
+	}
+	chr := make([]byte, 0x40000)
+	for i := range chr {
+		chr[i] = byte(i / 0x0400)
+	}
+	return Config{Submapper: submapper, PRG: prg, CHR: chr, PRGRAMSize: 0x2000}
+}
+
+func cpuRead(m Mapper, addr uint16) byte {
+	data, _ := m.CPURead(addr, true)
+	return data
+}
+
+func ppuRead(m Mapper, addr uint16) byte {
+	data, _ := m.PPURead(addr)
+	return data
+}
+
+func TestVRC2And4Wiring(t *testing.T) {
+	tests := []struct {
+		id        uint16
+		submapper uint8
+		a0, a1    uint16
+		vrc2      bool
+		chrShift  uint
+	}{
+		{21, 1, 0x02, 0x04, false, 0},
+		{21, 2, 0x40, 0x80, false, 0},
+		{22, 0, 0x02, 0x01, true, 1},
+		{23, 1, 0x01, 0x02, false, 0},
+		{23, 2, 0x04, 0x08, false, 0},
+		{23, 3, 0x01, 0x02, true, 0},
+		{25, 1, 0x02, 0x01, false, 0},
+		{25, 2, 0x08, 0x04, false, 0},
+		{25, 3, 0x02, 0x01, true, 0},
+	}
+	for _, tt := range tests {
+		m, err := New(tt.id, bankedConfig(tt.submapper))
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := fmt.Sprintf("mapper %d/%d", tt.id, tt.submapper)
+		reg := func(base uint16, r int) uint16 {
+			addr := base
+			if r&0x01 != 0 {
+				addr |= tt.a0
+			}
+			if r&0x02 != 0 {
+				addr |= tt.a1
+			}
+			return addr
+		}
+
+		m.CPUWrite(reg(0x8000, 0), 3)
+		m.CPUWrite(reg(0xA000, 0), 5)
+		if got := cpuRead(m, 0x8000); got != 3 {
+			t.Errorf("%s: $8000 bank %d, want 3", name, got)
+		}
+		if got := cpuRead(m, 0xA000); got != 5 {
+			t.Errorf("%s: $A000 bank %d, want 5", name, got)
+		}
+		if got := cpuRead(m, 0xC000); got != 62 {
+			t.Errorf("%s: $C000 bank %d, want 62", name, got)
+		}
+		if got := cpuRead(m, 0xE000); got != 63 {
+			t.Errorf("%s: $E000 bank %d, want 63", name, got)
+		}
+
+		for slot := 0; slot < 8; slot++ {
+			value := 0x23 + slot*9
+			base := 0xB000 + uint16(slot/2)*0x1000
+			r := slot & 0x01 * 2
+			m.CPUWrite(reg(base, r), byte(value&0x0F))
+			m.CPUWrite(reg(base, r+1), byte(value>>4))
+			want := byte(value >> tt.chrShift)
+			if got := ppuRead(m, uint16(slot)*0x0400); got != want {
+				t.Errorf("%s: CHR slot %d bank %d, want %d", name, slot, got, want)
+			}
+		}
+
+		m.CPUWrite(reg(0x9000, 0), 0x01)
+		if got := m.Mirroring(); got != Horizontal {
+			t.Errorf("%s: mirroring %v, want horizontal", name, got)
+		}
+
+		// VRC4 swap mode and IRQ, VRC2 has neither
+		m.CPUWrite(reg(0x9000, 2), 0x02)
+		if tt.vrc2 {
+			if got := cpuRead(m, 0x8000); got != 3 {
+				t.Errorf("%s: $8000 bank %d after $9002, want 3", name, got)
+			}
+			continue
+		}
+		if got := cpuRead(m, 0x8000); got != 62 {
+			t.Errorf("%s: swapped $8000 bank %d, want 62", name, got)
+		}
+		if got := cpuRead(m, 0xC000); got != 3 {
+			t.Errorf("%s: swapped $C000 bank %d, want 3", name, got)
+		}
+
+		m.CPUWrite(reg(0xF000, 0), 0x0E)
+		m.CPUWrite(reg(0xF000, 1), 0x0F)
+		m.CPUWrite(reg(0xF000, 2), 0x06)
+		m.Clock()
+		if m.IRQ() {
+			t.Errorf("%s: IRQ one cycle early", name)
+		}
+		m.Clock()
+		if !m.IRQ() {
+			t.Errorf("%s: no IRQ after the counter wrapped", name)
+		}
+		m.CPUWrite(reg(0xF000, 3), 0x00)
+		if m.IRQ() {
+			t.Errorf("%s: IRQ not acknowledged", name)
+		}
+	}
+}
+
+func TestVRC6Wiring(t *testing.T) {
+	tests := []struct {
+		id     uint16
+		a0, a1 uint16
+	}{
+		{24, 0x01, 0x02},
+		{26, 0x02, 0x01},
+	}
+	for _, tt := range tests {
+		m, err := New(tt.id, bankedConfig(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reg := func(base uint16, r int) uint16 {
+			addr := base
+			if r&0x01 != 0 {
+				addr |= tt.a0
+			}
+			if r&0x02 != 0 {
+				addr |= tt.a1
+			}
+			return addr
+		}
+
+		m.CPUWrite(reg(0x8000, 0), 2)
+		m.CPUWrite(reg(0xC000, 0), 7)
+		for addr, want := range map[uint16]byte{0x8000: 4, 0xA000: 5, 0xC000: 7, 0xE000: 63} {
+			if got := cpuRead(m, addr); got != want {
+				t.Errorf("mapper %d: $%04X bank %d, want %d", tt.id, addr, got, want)
+			}
+		}
+
+		for slot := 0; slot < 8; slot++ {
+			base := uint16(0xD000)
+			if slot >= 4 {
+				base = 0xE000
+			}
+			m.CPUWrite(reg(base, slot&0x03), byte(0x40+slot))
+			if got := ppuRead(m, uint16(slot)*0x0400); got != byte(0x40+slot) {
+				t.Errorf("mapper %d: CHR slot %d bank %d, want %d", tt.id, slot, got, 0x40+slot)
+			}
+		}
+
+		m.CPUWrite(0x6000, 0xAA)
+		if _, ok := m.CPURead(0x6000, true); ok {
+			t.Errorf("mapper %d: PRG RAM answered while disabled", tt.id)
+		}
+		m.CPUWrite(reg(0xB000, 3), 0x84)
+		if got := m.Mirroring(); got != Horizontal {
+			t.Errorf("mapper %d: mirroring %v, want horizontal", tt.id, got)
+		}
+		m.CPUWrite(0x6000, 0xAA)
+		if got := cpuRead(m, 0x6000); got != 0xAA {
+			t.Errorf("mapper %d: PRG RAM read %02X, want AA", tt.id, got)
+		}
+
+		m.CPUWrite(reg(0xF000, 0), 0xFF)
+		m.CPUWrite(reg(0xF000, 1), 0x06)
+		m.Clock()
+		if !m.IRQ() {
+			t.Errorf("mapper %d: no IRQ after the counter wrapped", tt.id)
+		}
+		m.CPUWrite(reg(0xF000, 2), 0x00)
+		if m.IRQ() {
+			t.Errorf("mapper %d: IRQ not acknowledged", tt.id)
+		}
+	}
+}
+
+func TestVRC7Wiring(t *testing.T) {
+	tests := []struct {
+		submapper uint8
+		line      uint16
+	}{
+		{1, 0x08},
+		{2, 0x10},
+	}
+	for _, tt := range tests {
+		m, err := New(85, bankedConfig(tt.submapper))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m.CPUWrite(0x8000, 3)
+		m.CPUWrite(0x8000|tt.line, 5)
+		m.CPUWrite(0x9000, 7)
+		// $9010 and $9030 are the audio ports and leave the banks alone
+		m.CPUWrite(0x9010, 9)
+		m.CPUWrite(0x9030, 9)
+		for addr, want := range map[uint16]byte{0x8000: 3, 0xA000: 5, 0xC000: 7, 0xE000: 63} {
+			if got := cpuRead(m, addr); got != want {
+				t.Errorf("mapper 85/%d: $%04X bank %d, want %d", tt.submapper, addr, got, want)
+			}
+		}
+
+		for slot := 0; slot < 8; slot++ {
+			addr := 0xA000 + uint16(slot/2)*0x1000
+			if slot&0x01 != 0 {
+				addr |= tt.line
+			}
+			m.CPUWrite(addr, byte(0x80+slot))
+			if got := ppuRead(m, uint16(slot)*0x0400); got != byte(0x80+slot) {
+				t.Errorf("mapper 85/%d: CHR slot %d bank %d, want %d", tt.submapper, slot, got, 0x80+slot)
+			}
+		}
+
+		m.CPUWrite(0xE000, 0x81)
+		if got := m.Mirroring(); got != Horizontal {
+			t.Errorf("mapper 85/%d: mirroring %v, want horizontal", tt.submapper, got)
+		}
+		m.CPUWrite(0x6000, 0x55)
+		if got := cpuRead(m, 0x6000); got != 0x55 {
+			t.Errorf("mapper 85/%d: PRG RAM read %02X, want 55", tt.submapper, got)
+		}
+
+		m.CPUWrite(0xE000|tt.line, 0xFF)
+		m.CPUWrite(0xF000, 0x06)
+		m.Clock()
+		if !m.IRQ() {
+			t.Errorf("mapper 85/%d: no IRQ after the counter wrapped", tt.submapper)
+		}
+		m.CPUWrite(0xF000|tt.line, 0x00)
+		if m.IRQ() {
+			t.Errorf("mapper 85/%d: IRQ not acknowledged", tt.submapper)
+		}
+	}
+}
+
+func TestVRCIRQCycleMode(t *testing.T) {
+	irq := vrcIRQ{Latch: 0xFD}
+	irq.control(0x07)
+	for i := 0; i < 2; i++ {
+		irq.clock()
+		if irq.Pending {
+			t.Fatalf("pending after %d cycles, want 3", i+1)
+		}
+	}
+	irq.clock()
+	if !irq.Pending || irq.Counter != 0xFD {
+		t.Fatalf("after 3 cycles: pending %v counter %02X, want reload to FD", irq.Pending, irq.Counter)
+	}
+
+	// Acknowledging copies the enable-after-acknowledge bit back
+	irq.acknowledge()
+	if irq.Pending || !irq.Enabled {
+		t.Fatalf("after acknowledge: pending %v enabled %v", irq.Pending, irq.Enabled)
+	}
+	irq.control(0x04)
+	irq.acknowledge()
+	counter := irq.Counter
+	irq.clock()
+	if irq.Enabled || irq.Counter != counter {
+		t.Errorf("disabled counter moved from %02X to %02X", counter, irq.Counter)
+	}
+}
+
+func TestVRCIRQScanlineMode(t *testing.T) {
+	irq := vrcIRQ{Latch: 0xFE}
+	irq.control(0x02)
+
+	// The prescaler ticks every 341 PPU dots, 113 or 114 CPU cycles
+	for i := 0; i < 113; i++ {
+		irq.clock()
+	}
+	if irq.Counter != 0xFE {
+		t.Fatalf("counter %02X after 113 cycles, want FE", irq.Counter)
+	}
+	irq.clock()
+	if irq.Counter != 0xFF {
+		t.Fatalf("counter %02X after 114 cycles, want FF", irq.Counter)
+	}
+	for i := 114; i < 227; i++ {
+		irq.clock()
+	}
+	if irq.Pending {
+		t.Fatal("pending after 227 cycles, want 228")
+	}
+	irq.clock()
+	if !irq.Pending || irq.Counter != 0xFE {
+		t.Fatalf("after 228 cycles: pending %v counter %02X", irq.Pending, irq.Counter)
+	}
+
+	// Three scanlines take exactly 341 CPU cycles
+	irq = vrcIRQ{Latch: 0xFD}
+	irq.control(0x02)
+	for i := 0; i < 340; i++ {
+		irq.clock()
+	}
+	if irq.Pending {
+		t.Fatal("pending after 340 cycles, want 341")
+	}
+	irq.clock()
+	if !irq.Pending {
+		t.Fatal("not pending after 341 cycles")
+	}
+}
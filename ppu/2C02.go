@@ -1,30 +1,193 @@
 package ppu
 
-var tblName = [2][1024]uint8{}
-var tblPallete = [32]uint8{}
+import (
+	"image"
+
+	"github.com/patrickn2/gonesemulator/mapper"
+)
+
+// Cartridge is the part of the cartridge wired to the PPU bus. Reads and
+// writes report whether the cartridge answered the address. Mirroring
+// decides which nametable page answers the addresses it leaves alone.
+// Every fetch goes through PPURead, which is how scanline counters such
+// as the MMC3 follow A12 while the bus clocks them once per CPU cycle.
+type Cartridge interface {
+	PPURead(addr uint16) (byte, bool)
+	PPUWrite(addr uint16, data byte) bool
+	Mirroring() mapper.Mirroring
+}
+
+// PPUCTRL bits
+const (
+	ctrlNametableX        byte = 1 << 0
+	ctrlNametableY        byte = 1 << 1
+	ctrlIncrementMode     byte = 1 << 2
+	ctrlPatternSprite     byte = 1 << 3
+	ctrlPatternBackground byte = 1 << 4
+	ctrlSpriteSize        byte = 1 << 5
+	ctrlSlaveMode         byte = 1 << 6 // unused
+	ctrlEnableNMI         byte = 1 << 7
+)
+
+// PPUMASK bits
+const (
+	maskGrayscale            byte = 1 << 0
+	maskRenderBackgroundLeft byte = 1 << 1
+	maskRenderSpritesLeft    byte = 1 << 2
+	maskRenderBackground     byte = 1 << 3
+	maskRenderSprites        byte = 1 << 4
+	maskEnhanceRed           byte = 1 << 5
+	maskEnhanceGreen         byte = 1 << 6
+	maskEnhanceBlue          byte = 1 << 7
+)
+
+// PPUSTATUS bits
+const (
+	statusSpriteOverflow byte = 1 << 5
+	statusSpriteZeroHit  byte = 1 << 6
+	statusVerticalBlank  byte = 1 << 7
+)
+
+// Internal "loopy" address layout used by v and t: yyy NN YYYYY XXXXX
+const (
+	loopyCoarseX    uint16 = 0x001F
+	loopyCoarseY    uint16 = 0x03E0
+	loopyNametableX uint16 = 0x0400
+	loopyNametableY uint16 = 0x0800
+	loopyFineY      uint16 = 0x7000
+)
+
+type ppu struct {
+	cart       Cartridge
+	tblName    [4][1024]uint8 // pages 2 and 3 stand in for four-screen VRAM
+	tblPallete [32]uint8
+
+	control byte
+	mask    byte
+	status  byte
+
+	oamAddr byte
+	oam     [256]byte
+
+	vramAddr     uint16 // v
+	tramAddr     uint16 // t
+	fineX        byte   // x
+	addressLatch bool   // w
+	dataBuffer   byte
+	ioLatch      byte
+
+	scanline      int
+	cycle         int
+	oddFrame      bool
+	frameComplete bool
+	frame         *image.Paletted
+
+	bgNextTileID       byte
+	bgNextTileAttrib   byte
+	bgNextTileLsb      byte
+	bgNextTileMsb      byte
+	bgShifterPatternLo uint16
+	bgShifterPatternHi uint16
+	bgShifterAttribLo  uint16
+	bgShifterAttribHi  uint16
+
+	secondaryOAM     [32]byte
+	spriteCount      int
+	spriteZeroOnLine bool
+	spritePatternLo  [maxSpritesPerScanline]byte
+	spritePatternHi  [maxSpritesPerScanline]byte
+	spriteAttrib     [maxSpritesPerScanline]byte
+	spriteX          [maxSpritesPerScanline]byte
+}
+
+func New() *ppu {
+	return &ppu{
+		scanline: preRenderScanline,
+		frame:    image.NewPaletted(image.Rect(0, 0, ScreenWidth, ScreenHeight), Palette),
+	}
+}
+
+// Public Methods
+
+func (p *ppu) ConnectCartridge(cart Cartridge) {
+	p.cart = cart
+}
+
+// Reset clears the registers the way the /RST line does, memory is kept
+func (p *ppu) Reset() {
+	p.control = 0x00
+	p.mask = 0x00
+	p.addressLatch = false
+	p.fineX = 0x00
+	p.tramAddr = 0x0000
+	p.dataBuffer = 0x00
+	p.scanline = preRenderScanline
+	p.cycle = 0
+	p.oddFrame = false
+}
+
+// Frame is the picture being drawn, one palette index per pixel. It is
+// complete whenever FrameComplete reports true.
+func (p *ppu) Frame() *image.Paletted {
+	return p.frame
+}
+
+// FrameComplete reports whether a frame was finished since the last call
+func (p *ppu) FrameComplete() bool {
+	complete := p.frameComplete
+	p.frameComplete = false
+	return complete
+}
+
+// NMI reports the level of the PPU /NMI output, active while vertical
+// blank is flagged and NMI generation is enabled in PPUCTRL
+func (p *ppu) NMI() bool {
+	return p.status&statusVerticalBlank != 0 && p.control&ctrlEnableNMI != 0
+}
+
+func (p *ppu) CPUWrite(addr uint16, data byte) {
+	p.ioLatch = data
 
-func CPUWrite(addr uint16, data byte) {
 	switch addr {
 	case 0x0000: // Control
-		break
+		p.control = data
+		p.tramAddr = p.tramAddr&^(loopyNametableX|loopyNametableY) | uint16(data&0x03)<<10
 	case 0x0001: // Mask
-		break
+		p.mask = data
 	case 0x0002: // Status
 		break
 	case 0x0003: // OAM Address
-		break
+		p.oamAddr = data
 	case 0x0004: // OAM Data
-		break
+		p.oam[p.oamAddr] = data
+		p.oamAddr++
 	case 0x0005: // Scroll
-		break
+		if !p.addressLatch {
+			p.fineX = data & 0x07
+			p.tramAddr = p.tramAddr&^loopyCoarseX | uint16(data>>3)
+		} else {
+			p.tramAddr = p.tramAddr&^(loopyFineY|loopyCoarseY) |
+				uint16(data&0x07)<<12 | uint16(data>>3)<<5
+		}
+		p.addressLatch = !p.addressLatch
 	case 0x0006: // PPU Address
-		break
+		if !p.addressLatch {
+			// The top bit of v is lost, the PPU bus is only 14 bits wide
+			p.tramAddr = p.tramAddr&0x00FF | uint16(data&0x3F)<<8
+		} else {
+			p.tramAddr = p.tramAddr&0xFF00 | uint16(data)
+			p.vramAddr = p.tramAddr
+		}
+		p.addressLatch = !p.addressLatch
 	case 0x0007: // PPU Data
-		break
+		p.PPUWrite(p.vramAddr, data)
+		p.incrementVRAMAddr()
 	}
 }
 
-func CPURead(addr uint16, bReadOnly bool) byte {
+func (p *ppu) CPURead(addr uint16, bReadOnly bool) byte {
+	// Write only registers answer with whatever is left on the I/O latch
+	data := p.ioLatch
 
 	switch addr {
 	case 0x0000: // Control
@@ -32,27 +195,126 @@ func CPURead(addr uint16, bReadOnly bool) byte {
 	case 0x0001: // Mask
 		break
 	case 0x0002: // Status
-		break
+		data = p.status&0xE0 | p.ioLatch&0x1F
+		if !bReadOnly {
+			p.status &^= statusVerticalBlank
+			p.addressLatch = false
+		}
 	case 0x0003: // OAM Address
 		break
 	case 0x0004: // OAM Data
-		break
+		data = p.oam[p.oamAddr]
+		// Bits 2-4 of the sprite attribute byte do not exist in OAM
+		if p.oamAddr&0x03 == 0x02 {
+			data &= 0xE3
+		}
 	case 0x0005: // Scroll
 		break
 	case 0x0006: // PPU Address
 		break
 	case 0x0007: // PPU Data
-		break
+		// A peek answers from the buffer and the palette, the cartridge
+		// would see a fetch
+		if bReadOnly {
+			if p.vramAddr&0x3FFF >= 0x3F00 {
+				return p.PPURead(p.vramAddr, true)&0x3F | p.ioLatch&0xC0
+			}
+			return p.dataBuffer
+		}
+		// Reads are delayed by one access through the internal buffer,
+		// except for the palette which answers immediately while the
+		// buffer is filled with the nametable byte "underneath" it
+		data = p.dataBuffer
+		p.dataBuffer = p.PPURead(p.vramAddr, false)
+		if p.vramAddr&0x3FFF >= 0x3F00 {
+			data = p.dataBuffer&0x3F | p.ioLatch&0xC0
+			p.dataBuffer = p.PPURead(p.vramAddr-0x1000, false)
+		}
+		p.incrementVRAMAddr()
+	}
+
+	if !bReadOnly {
+		p.ioLatch = data
 	}
-	return 0x00
+	return data
 }
 
-func PPURead(addr uint16, bReadOnly bool) byte {
+func (p *ppu) PPURead(addr uint16, bReadOnly bool) byte {
 
 	addr &= 0x3FFF
-	return uint8(addr)
+
+	// Palette RAM lives inside the PPU and never reaches the cartridge
+	if addr >= 0x3F00 {
+		data := p.tblPallete[paletteIndex(addr)]
+		if p.mask&maskGrayscale != 0 {
+			data &= 0x30
+		}
+		return data & 0x3F
+	}
+
+	if p.cart != nil {
+		if data, ok := p.cart.PPURead(addr); ok {
+			return data
+		}
+	}
+
+	if addr <= 0x1FFF {
+		return 0x00
+	}
+	return p.nametable(addr)[addr&0x03FF]
 }
 
-func PPUWrite(addr uint16, data byte) {
+func (p *ppu) PPUWrite(addr uint16, data byte) {
 	addr &= 0x3FFF
+
+	if addr >= 0x3F00 {
+		p.tblPallete[paletteIndex(addr)] = data & 0x3F
+		return
+	}
+
+	if p.cart != nil && p.cart.PPUWrite(addr, data) {
+		return
+	}
+
+	if addr <= 0x1FFF {
+		return
+	}
+	p.nametable(addr)[addr&0x03FF] = data
+}
+
+// Private Methods
+
+// nametable resolves $2000-$3EFF through the cartridge mirroring, which
+// boards may change at any time
+func (p *ppu) nametable(addr uint16) *[1024]uint8 {
+	mirroring := mapper.Vertical
+	if p.cart != nil {
+		mirroring = p.cart.Mirroring()
+	}
+	return &p.tblName[mirroring.Page(addr)]
+}
+
+func (p *ppu) incrementVRAMAddr() {
+	// During rendering the access bumps v through both scroll counters
+	if p.rendering() {
+		p.incrementScrollX()
+		p.incrementScrollY()
+		return
+	}
+	if p.control&ctrlIncrementMode != 0 {
+		p.vramAddr += 32
+	} else {
+		p.vramAddr++
+	}
+	p.vramAddr &= 0x7FFF
+}
+
+// paletteIndex folds $3F00-$3FFF onto the 32 bytes of palette RAM, the
+// backdrop entries of the sprite palettes mirror the background ones
+func paletteIndex(addr uint16) uint16 {
+	addr &= 0x001F
+	if addr&0x0013 == 0x0010 {
+		addr &= 0x000F
+	}
+	return addr
 }
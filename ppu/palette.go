@@ -0,0 +1,75 @@
+package ppu
+
+import "image/color"
+
+// Palette holds the 64 colours the 2C02 can output, indexed by the
+// 6 bit values stored in palette RAM
+var Palette = color.Palette{
+	color.RGBA{84, 84, 84, 255},
+	color.RGBA{0, 30, 116, 255},
+	color.RGBA{8, 16, 144, 255},
+	color.RGBA{48, 0, 136, 255},
+	color.RGBA{68, 0, 100, 255},
+	color.RGBA{92, 0, 48, 255},
+	color.RGBA{84, 4, 0, 255},
+	color.RGBA{60, 24, 0, 255},
+	color.RGBA{32, 42, 0, 255},
+	color.RGBA{8, 58, 0, 255},
+	color.RGBA{0, 64, 0, 255},
+	color.RGBA{0, 60, 0, 255},
+	color.RGBA{0, 50, 60, 255},
+	color.RGBA{0, 0, 0, 255},
+	color.RGBA{0, 0, 0, 255},
+	color.RGBA{0, 0, 0, 255},
+
+	color.RGBA{152, 150, 152, 255},
+	color.RGBA{8, 76, 196, 255},
+	color.RGBA{48, 50, 236, 255},
+	color.RGBA{92, 30, 228, 255},
+	color.RGBA{136, 20, 176, 255},
+	color.RGBA{160, 20, 100, 255},
+	color.RGBA{152, 34, 32, 255},
+	color.RGBA{120, 60, 0, 255},
+	color.RGBA{84, 90, 0, 255},
+	color.RGBA{40, 114, 0, 255},
+	color.RGBA{8, 124, 0, 255},
+	color.RGBA{0, 118, 40, 255},
+	color.RGBA{0, 102, 120, 255},
+	color.RGBA{0, 0, 0, 255},
+	color.RGBA{0, 0, 0, 255},
+	color.RGBA{0, 0, 0, 255},
+
+	color.RGBA{236, 238, 236, 255},
+	color.RGBA{76, 154, 236, 255},
+	color.RGBA{120, 124, 236, 255},
+	color.RGBA{176, 98, 236, 255},
+	color.RGBA{228, 84, 236, 255},
+	color.RGBA{236, 88, 180, 255},
+	color.RGBA{236, 106, 100, 255},
+	color.RGBA{212, 136, 32, 255},
+	color.RGBA{160, 170, 0, 255},
+	color.RGBA{116, 196, 0, 255},
+	color.RGBA{76, 208, 32, 255},
+	color.RGBA{56, 204, 108, 255},
+	color.RGBA{56, 180, 204, 255},
+	color.RGBA{60, 60, 60, 255},
+	color.RGBA{0, 0, 0, 255},
+	color.RGBA{0, 0, 0, 255},
+
+	color.RGBA{236, 238, 236, 255},
+	color.RGBA{168, 204, 236, 255},
+	color.RGBA{188, 188, 236, 255},
+	color.RGBA{212, 178, 236, 255},
+	color.RGBA{236, 174, 236, 255},
+	color.RGBA{236, 174, 212, 255},
+	color.RGBA{236, 180, 176, 255},
+	color.RGBA{228, 196, 144, 255},
+	color.RGBA{204, 210, 120, 255},
+	color.RGBA{180, 222, 120, 255},
+	color.RGBA{168, 226, 144, 255},
+	color.RGBA{152, 226, 180, 255},
+	color.RGBA{160, 214, 228, 255},
+	color.RGBA{160, 162, 160, 255},
+	color.RGBA{0, 0, 0, 255},
+	color.RGBA{0, 0, 0, 255},
+}
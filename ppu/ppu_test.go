@@ -0,0 +1,410 @@
+package ppu
+
+import (
+	"testing"
+
+	"github.com/patrickn2/gonesemulator/mapper"
+)
+
+// testCartridge is 8KB of CHR RAM that counts the fetches it answers
+type testCartridge struct {
+	chr   [0x2000]byte
+	reads int
+}
+
+func (c *testCartridge) PPURead(addr uint16) (byte, bool) {
+	c.reads++
+	if addr <= 0x1FFF {
+		return c.chr[addr], true
+	}
+	return 0x00, false
+}
+
+func (c *testCartridge) PPUWrite(addr uint16, data byte) bool {
+	if addr <= 0x1FFF {
+		c.chr[addr] = data
+		return true
+	}
+	return false
+}
+
+func (c *testCartridge) Mirroring() mapper.Mirroring {
+	return mapper.Vertical
+}
+
+func newTestPPU() (*ppu, *testCartridge) {
+	p := New()
+	cart := &testCartridge{}
+	p.ConnectCartridge(cart)
+	return p, cart
+}
+
+// setAddress points v at addr through $2006
+func setAddress(p *ppu, addr uint16) {
+	p.CPUWrite(0x0006, byte(addr>>8))
+	p.CPUWrite(0x0006, byte(addr))
+}
+
+func TestDataPeek(t *testing.T) {
+	p, cart := newTestPPU()
+	cart.chr[0x0123] = 0x42
+	setAddress(p, 0x0123)
+	p.CPURead(0x0007, false)
+
+	reads := cart.reads
+	for i := 0; i < 3; i++ {
+		if got := p.CPURead(0x0007, true); got != 0x42 {
+			t.Fatalf("peek %d: got %02X, want the buffered 42", i, got)
+		}
+	}
+	if cart.reads != reads || p.vramAddr != 0x0124 {
+		t.Errorf("peeks fetched %d times and moved v to $%04X", cart.reads-reads, p.vramAddr)
+	}
+
+	setAddress(p, 0x3F01)
+	p.CPUWrite(0x0007, 0x2A)
+	setAddress(p, 0x3F01)
+	reads = cart.reads
+	if got := p.CPURead(0x0007, true); got&0x3F != 0x2A || cart.reads != reads {
+		t.Errorf("palette peek: got %02X with %d fetches, want 2A and none", got, cart.reads-reads)
+	}
+}
+
+func TestLoopyRegisters(t *testing.T) {
+	p, _ := newTestPPU()
+
+	// $2000 puts the nametable select into t bits 10-11
+	p.CPUWrite(0x0000, 0x03)
+	if p.tramAddr != 0x0C00 {
+		t.Errorf("$2000=03: t=$%04X, want $0C00", p.tramAddr)
+	}
+
+	// First $2005 write sets coarse X and fine X, the second fine and
+	// coarse Y
+	p.CPUWrite(0x0000, 0x00)
+	p.CPUWrite(0x0005, 0x7D)
+	if p.tramAddr != 0x000F || p.fineX != 0x05 || !p.addressLatch {
+		t.Errorf("$2005=7D: t=$%04X x=%d w=%v, want $000F 5 true", p.tramAddr, p.fineX, p.addressLatch)
+	}
+	p.CPUWrite(0x0005, 0x5E)
+	if p.tramAddr != 0x616F || p.addressLatch {
+		t.Errorf("$2005=5E: t=$%04X w=%v, want $616F false", p.tramAddr, p.addressLatch)
+	}
+
+	// $2006 writes the high six bits, clearing bit 14, then the low byte
+	// which also copies t into v
+	p.CPUWrite(0x0006, 0xFD)
+	if p.tramAddr != 0x3D6F || p.vramAddr != 0x0000 {
+		t.Errorf("$2006=FD: t=$%04X v=$%04X, want $3D6F $0000", p.tramAddr, p.vramAddr)
+	}
+	p.CPUWrite(0x0006, 0x12)
+	if p.tramAddr != 0x3D12 || p.vramAddr != 0x3D12 || p.addressLatch {
+		t.Errorf("$2006=12: t=$%04X v=$%04X w=%v, want $3D12 $3D12 false", p.tramAddr, p.vramAddr, p.addressLatch)
+	}
+
+	// $2007 moves v by 1, or 32 with PPUCTRL bit 2
+	setAddress(p, 0x2000)
+	p.CPUWrite(0x0007, 0x00)
+	if p.vramAddr != 0x2001 {
+		t.Errorf("increment 1: v=$%04X, want $2001", p.vramAddr)
+	}
+	p.CPUWrite(0x0000, 0x04)
+	p.CPUWrite(0x0007, 0x00)
+	if p.vramAddr != 0x2021 {
+		t.Errorf("increment 32: v=$%04X, want $2021", p.vramAddr)
+	}
+}
+
+func TestDataReadBuffer(t *testing.T) {
+	p, cart := newTestPPU()
+	cart.chr[0x0010] = 0x11
+	cart.chr[0x0011] = 0x22
+
+	setAddress(p, 0x0010)
+	p.CPURead(0x0007, false)
+	if got := p.CPURead(0x0007, false); got != 0x11 {
+		t.Errorf("delayed read: got %02X, want 11", got)
+	}
+	if got := p.CPURead(0x0007, false); got != 0x22 {
+		t.Errorf("delayed read: got %02X, want 22", got)
+	}
+
+	// Nametables mirror through the cartridge arrangement, vertical here
+	setAddress(p, 0x2405)
+	p.CPUWrite(0x0007, 0x77)
+	setAddress(p, 0x2C05)
+	p.CPURead(0x0007, false)
+	if got := p.CPURead(0x0007, false); got != 0x77 {
+		t.Errorf("$2C05 mirror of $2405: got %02X, want 77", got)
+	}
+
+	// The palette answers at once, $3F10 mirrors $3F00
+	setAddress(p, 0x3F10)
+	p.CPUWrite(0x0007, 0x2C)
+	setAddress(p, 0x3F00)
+	if got := p.CPURead(0x0007, false) & 0x3F; got != 0x2C {
+		t.Errorf("palette read: got %02X, want 2C", got)
+	}
+}
+
+func TestStatusRead(t *testing.T) {
+	p, _ := newTestPPU()
+	p.status = statusVerticalBlank | statusSpriteZeroHit
+	p.CPUWrite(0x0003, 0x1F) // leaves $1F on the I/O latch
+
+	if got := p.CPURead(0x0002, true); got != 0xDF {
+		t.Errorf("peek: got %02X, want DF", got)
+	}
+	if p.status&statusVerticalBlank == 0 {
+		t.Error("peek cleared vertical blank")
+	}
+
+	p.CPUWrite(0x0005, 0x08) // w is now set, and the latch holds $08
+	if got := p.CPURead(0x0002, false); got != 0xC8 {
+		t.Errorf("read: got %02X, want C8", got)
+	}
+	if got := p.CPURead(0x0002, false); got&0xE0 != 0x40 {
+		t.Errorf("second read: got %02X, want vertical blank cleared", got)
+	}
+	if p.addressLatch {
+		t.Error("reading $2002 left w set")
+	}
+	p.CPUWrite(0x0005, 0x10)
+	if p.tramAddr&loopyCoarseX != 0x02 {
+		t.Errorf("write after $2002 read was not a first write, t=$%04X", p.tramAddr)
+	}
+}
+
+// runTo clocks the PPU until it is about to run the given dot
+func runTo(p *ppu, scanline, cycle int) {
+	for p.scanline != scanline || p.cycle != cycle {
+		p.Clock()
+	}
+}
+
+func TestVerticalBlankNMI(t *testing.T) {
+	p, _ := newTestPPU()
+	p.CPUWrite(0x0000, 0x80)
+
+	runTo(p, vblankScanline, 1)
+	if p.NMI() {
+		t.Fatal("NMI before vertical blank")
+	}
+	p.Clock()
+	if !p.NMI() {
+		t.Fatal("no NMI at scanline 241 dot 1")
+	}
+
+	// Reading $2002 drops the line, enabling NMI again during vertical
+	// blank raises it once more only while the flag is still set
+	p.CPURead(0x0002, false)
+	if p.NMI() {
+		t.Error("NMI still active after $2002 read")
+	}
+	p.CPUWrite(0x0000, 0x00)
+	p.CPUWrite(0x0000, 0x80)
+	if p.NMI() {
+		t.Error("NMI raised with vertical blank already acknowledged")
+	}
+
+	runTo(p, vblankScanline+1, 0)
+	p.status |= statusVerticalBlank
+	p.CPUWrite(0x0000, 0x00)
+	if p.NMI() {
+		t.Error("NMI active with PPUCTRL bit 7 clear")
+	}
+	p.CPUWrite(0x0000, 0x80)
+	if !p.NMI() {
+		t.Error("enabling NMI during vertical blank did not raise it")
+	}
+
+	// The pre-render line clears the flag and with it the NMI
+	runTo(p, preRenderScanline, 2)
+	if p.NMI() || p.status&statusVerticalBlank != 0 {
+		t.Error("vertical blank not cleared on the pre-render line")
+	}
+	if !p.FrameComplete() || p.FrameComplete() {
+		t.Error("FrameComplete did not report the finished frame once")
+	}
+}
+
+func TestFrameBuffer(t *testing.T) {
+	p, cart := newTestPPU()
+
+	// Tile 1 is solid colour 1, tile 2 solid colour 3
+	for row := 0; row < 8; row++ {
+		cart.chr[0x10+row] = 0xFF
+		cart.chr[0x20+row] = 0xFF
+		cart.chr[0x28+row] = 0xFF
+	}
+	setAddress(p, 0x2000)
+	p.CPUWrite(0x0007, 0x01)
+	p.CPUWrite(0x0007, 0x02)
+	// Attribute of the top left 32x32 area selects palette 1 for it
+	setAddress(p, 0x23C0)
+	p.CPUWrite(0x0007, 0x01)
+
+	setAddress(p, 0x3F00)
+	for _, colour := range []byte{0x0F, 0x16, 0x27, 0x18, 0x0F, 0x21, 0x22, 0x23} {
+		p.CPUWrite(0x0007, colour)
+	}
+	p.CPUWrite(0x0000, 0x00)
+	p.CPUWrite(0x0005, 0x00)
+	p.CPUWrite(0x0005, 0x00)
+	p.CPUWrite(0x0001, 0x0A)
+
+	runTo(p, vblankScanline, 0)
+	frame := p.Frame()
+	tests := []struct {
+		x, y int
+		want uint8
+	}{
+		{0, 0, 0x21},
+		{7, 7, 0x21},
+		{8, 0, 0x23},
+		{15, 7, 0x23},
+		{16, 0, 0x0F},
+		{0, 8, 0x0F},
+		{255, 239, 0x0F},
+	}
+	for _, tt := range tests {
+		if got := frame.ColorIndexAt(tt.x, tt.y); got != tt.want {
+			t.Errorf("pixel %d,%d: got %02X, want %02X", tt.x, tt.y, got, tt.want)
+		}
+	}
+
+	// Hiding the left column shows the backdrop there
+	p.CPUWrite(0x0001, 0x08)
+	runTo(p, vblankScanline, 0)
+	runTo(p, vblankScanline-1, 0)
+	if got := frame.ColorIndexAt(0, 0); got != 0x0F {
+		t.Errorf("hidden left column: got %02X, want 0F", got)
+	}
+	if got := frame.ColorIndexAt(8, 0); got != 0x23 {
+		t.Errorf("next to the left column: got %02X, want 23", got)
+	}
+}
+
+func TestRenderingDisabledFetchesNothing(t *testing.T) {
+	p, cart := newTestPPU()
+	runTo(p, vblankScanline, 0)
+	runTo(p, vblankScanline-1, 0)
+	if cart.reads != 0 {
+		t.Errorf("%d cartridge reads in a frame with rendering disabled", cart.reads)
+	}
+
+	p.CPUWrite(0x0001, 0x08)
+	runTo(p, preRenderScanline, 0)
+	runTo(p, vblankScanline, 0)
+	if cart.reads == 0 {
+		t.Error("no cartridge reads in a frame with the background enabled")
+	}
+}
+
+// placeSprites fills OAM with hidden sprites and sets the given Y values
+// from sprite 0 on
+func placeSprites(p *ppu, ys ...byte) {
+	for i := range p.oam {
+		p.oam[i] = 0xFF
+	}
+	for n, y := range ys {
+		p.oam[n*4] = y
+		p.oam[n*4+1] = 0x00
+		p.oam[n*4+2] = 0x00
+		p.oam[n*4+3] = byte(n * 8)
+	}
+}
+
+func TestSpriteEvaluation(t *testing.T) {
+	p, _ := newTestPPU()
+	p.scanline = 20
+
+	placeSprites(p, 10, 13, 20, 21)
+	p.evaluateSprites()
+	if p.spriteCount != 2 || p.spriteZeroOnLine {
+		t.Errorf("sprites at 10, 13, 20, 21: %d on line 20, sprite 0 %v, want 2 and false", p.spriteCount, p.spriteZeroOnLine)
+	}
+	if p.secondaryOAM[0] != 13 || p.secondaryOAM[4] != 20 || p.secondaryOAM[8] != 0xFF {
+		t.Errorf("secondary OAM % X", p.secondaryOAM[:12])
+	}
+
+	p.control = ctrlSpriteSize
+	p.evaluateSprites()
+	if p.spriteCount != 3 || !p.spriteZeroOnLine {
+		t.Errorf("8x16: %d sprites on line 20, sprite 0 %v, want 3 and true", p.spriteCount, p.spriteZeroOnLine)
+	}
+	p.control = 0x00
+
+	placeSprites(p, 20, 20, 20, 20, 20, 20, 20, 20)
+	p.evaluateSprites()
+	if p.spriteCount != 8 || p.status&statusSpriteOverflow != 0 {
+		t.Errorf("eight sprites: count %d, overflow %v", p.spriteCount, p.status&statusSpriteOverflow != 0)
+	}
+
+	placeSprites(p, 20, 20, 20, 20, 20, 20, 20, 20, 20)
+	p.evaluateSprites()
+	if p.spriteCount != 8 || p.status&statusSpriteOverflow == 0 {
+		t.Errorf("nine sprites: count %d, overflow %v", p.spriteCount, p.status&statusSpriteOverflow != 0)
+	}
+}
+
+func TestSpriteOverflowBug(t *testing.T) {
+	p, _ := newTestPPU()
+	p.scanline = 20
+
+	// After the eighth sprite, a miss on sprite 8 makes the next check
+	// read the tile byte of sprite 9 as its Y
+	placeSprites(p, 20, 20, 20, 20, 20, 20, 20, 20, 0x80, 20)
+	p.oam[9*4+1] = 0x80
+	p.evaluateSprites()
+	if p.status&statusSpriteOverflow != 0 {
+		t.Error("overflow set from a Y coordinate the buggy check skips")
+	}
+
+	p.status = 0x00
+	p.oam[9*4] = 0x80
+	p.oam[9*4+1] = 18
+	p.evaluateSprites()
+	if p.status&statusSpriteOverflow == 0 {
+		t.Error("overflow not set from a tile byte read as Y")
+	}
+}
+
+func TestSpriteZeroHit(t *testing.T) {
+	p, cart := newTestPPU()
+
+	// Tile 1 is opaque everywhere, the background shows it in the top
+	// left tile only and sprite 0 overlaps its last column on line 5
+	for row := 0; row < 8; row++ {
+		cart.chr[0x10+row] = 0xFF
+	}
+	setAddress(p, 0x2000)
+	p.CPUWrite(0x0007, 0x01)
+	placeSprites(p, 4)
+	p.oam[1] = 0x01
+	p.oam[3] = 0x07
+	p.CPUWrite(0x0000, 0x00)
+	p.CPUWrite(0x0005, 0x00)
+	p.CPUWrite(0x0005, 0x00)
+	p.CPUWrite(0x0001, 0x1E)
+
+	runTo(p, 5, 8)
+	if p.status&statusSpriteZeroHit != 0 {
+		t.Fatal("sprite 0 hit before the sprite is drawn")
+	}
+	p.Clock()
+	if p.status&statusSpriteZeroHit == 0 {
+		t.Fatal("no sprite 0 hit where sprite 0 meets the background")
+	}
+	runTo(p, preRenderScanline, 2)
+	if p.status&statusSpriteZeroHit != 0 {
+		t.Error("sprite 0 hit not cleared on the pre-render line")
+	}
+
+	// Clipping the left eight pixels of sprites hides the overlap
+	p.CPUWrite(0x0001, 0x1A)
+	runTo(p, vblankScanline, 0)
+	if p.status&statusSpriteZeroHit != 0 {
+		t.Error("sprite 0 hit inside the clipped left column")
+	}
+}
@@ -0,0 +1,251 @@
+package ppu
+
+// Frame timing of the NTSC 2C02
+const (
+	ScreenWidth  = 256
+	ScreenHeight = 240
+
+	dotsPerScanline   = 341
+	preRenderScanline = -1
+	vblankScanline    = 241
+	lastScanline      = 260
+)
+
+// Clock advances the PPU by one dot. Scanline -1 is the pre-render line,
+// 0-239 are visible, 240 is idle and 241-260 are vertical blank.
+func (p *ppu) Clock() {
+	if p.scanline >= preRenderScanline && p.scanline < ScreenHeight {
+		p.renderScanline()
+	}
+
+	if p.scanline == vblankScanline && p.cycle == 1 {
+		p.status |= statusVerticalBlank
+	}
+
+	if p.scanline >= 0 && p.scanline < ScreenHeight && p.cycle >= 1 && p.cycle <= ScreenWidth {
+		p.frame.Pix[p.scanline*ScreenWidth+p.cycle-1] = p.pixel()
+	}
+
+	p.cycle++
+	if p.cycle >= dotsPerScanline {
+		p.cycle = 0
+		p.scanline++
+		if p.scanline > lastScanline {
+			p.scanline = preRenderScanline
+			p.frameComplete = true
+			p.oddFrame = !p.oddFrame
+		}
+	}
+}
+
+// Private Methods
+
+func (p *ppu) renderScanline() {
+	// The idle dot of the first visible scanline is skipped on odd frames
+	if p.scanline == 0 && p.cycle == 0 && p.oddFrame && p.renderingEnabled() {
+		p.cycle = 1
+	}
+
+	if p.scanline == preRenderScanline && p.cycle == 1 {
+		p.status &^= statusVerticalBlank | statusSpriteZeroHit | statusSpriteOverflow
+	}
+
+	// The PPU leaves the bus alone while rendering is disabled, which
+	// mappers watching the fetches rely on
+	fetching := (p.cycle >= 2 && p.cycle < 258) || (p.cycle >= 321 && p.cycle < 338)
+	if fetching && p.renderingEnabled() {
+		p.updateShifters()
+
+		switch (p.cycle - 1) % 8 {
+		case 0:
+			p.loadBackgroundShifters()
+			p.bgNextTileID = p.PPURead(0x2000|p.vramAddr&0x0FFF, false)
+		case 2:
+			p.bgNextTileAttrib = p.PPURead(0x23C0|
+				p.vramAddr&(loopyNametableX|loopyNametableY)|
+				(p.vramAddr&loopyCoarseY)>>7<<3|
+				(p.vramAddr&loopyCoarseX)>>2, false)
+			// Each attribute byte covers a 4x4 tile area split in 2x2 quadrants
+			if p.vramAddr&0x0040 != 0 {
+				p.bgNextTileAttrib >>= 4
+			}
+			if p.vramAddr&0x0002 != 0 {
+				p.bgNextTileAttrib >>= 2
+			}
+			p.bgNextTileAttrib &= 0x03
+		case 4:
+			p.bgNextTileLsb = p.PPURead(p.backgroundTileAddr(), false)
+		case 6:
+			p.bgNextTileMsb = p.PPURead(p.backgroundTileAddr()+8, false)
+		case 7:
+			p.incrementScrollX()
+		}
+	}
+
+	if p.cycle == 256 {
+		p.incrementScrollY()
+	}
+
+	if p.cycle == 257 {
+		p.loadBackgroundShifters()
+		p.transferAddressX()
+		if p.renderingEnabled() {
+			p.evaluateSprites()
+		} else {
+			p.spriteCount = 0
+		}
+	}
+
+	if p.cycle >= 257 && p.cycle <= 320 && p.renderingEnabled() {
+		p.oamAddr = 0x00
+		p.fetchSprites()
+	}
+
+	// Unused nametable fetches at the end of the scanline
+	if (p.cycle == 338 || p.cycle == 340) && p.renderingEnabled() {
+		p.bgNextTileID = p.PPURead(0x2000|p.vramAddr&0x0FFF, false)
+	}
+
+	if p.scanline == preRenderScanline && p.cycle >= 280 && p.cycle < 305 {
+		p.transferAddressY()
+	}
+}
+
+func (p *ppu) pixel() uint8 {
+	var bgPixel, bgPalette byte
+
+	if p.mask&maskRenderBackground != 0 && (p.cycle > 8 || p.mask&maskRenderBackgroundLeft != 0) {
+		mux := uint16(0x8000) >> p.fineX
+
+		if p.bgShifterPatternLo&mux != 0 {
+			bgPixel |= 0x01
+		}
+		if p.bgShifterPatternHi&mux != 0 {
+			bgPixel |= 0x02
+		}
+		if p.bgShifterAttribLo&mux != 0 {
+			bgPalette |= 0x01
+		}
+		if p.bgShifterAttribHi&mux != 0 {
+			bgPalette |= 0x02
+		}
+	}
+
+	// With rendering off the backdrop is replaced by the palette entry v
+	// points at, which some games use to draw colour bars
+	if !p.renderingEnabled() && p.vramAddr&0x3F00 == 0x3F00 {
+		return p.PPURead(p.vramAddr, true)
+	}
+
+	spPixel, spAttrib, spriteZero := p.spritePixel()
+
+	// Sprite 0 hit needs both layers opaque, and never triggers on the
+	// last column
+	if spriteZero && bgPixel != 0 && p.cycle != ScreenWidth {
+		p.status |= statusSpriteZeroHit
+	}
+
+	var pixel, palette byte
+	switch {
+	case bgPixel == 0 && spPixel == 0:
+	case bgPixel == 0 || (spPixel != 0 && spAttrib&spriteBehindBackground == 0):
+		pixel = spPixel
+		palette = 0x04 + spAttrib&spritePalette
+	default:
+		pixel = bgPixel
+		palette = bgPalette
+	}
+	return p.PPURead(0x3F00+uint16(palette)<<2+uint16(pixel), true)
+}
+
+func (p *ppu) renderingEnabled() bool {
+	return p.mask&(maskRenderBackground|maskRenderSprites) != 0
+}
+
+func (p *ppu) rendering() bool {
+	return p.renderingEnabled() && p.scanline >= preRenderScanline && p.scanline < ScreenHeight
+}
+
+func (p *ppu) backgroundTileAddr() uint16 {
+	var table uint16
+	if p.control&ctrlPatternBackground != 0 {
+		table = 0x1000
+	}
+	return table + uint16(p.bgNextTileID)<<4 + (p.vramAddr&loopyFineY)>>12
+}
+
+func (p *ppu) incrementScrollX() {
+	if !p.renderingEnabled() {
+		return
+	}
+	if p.vramAddr&loopyCoarseX == 31 {
+		p.vramAddr &^= loopyCoarseX
+		p.vramAddr ^= loopyNametableX
+	} else {
+		p.vramAddr++
+	}
+}
+
+func (p *ppu) incrementScrollY() {
+	if !p.renderingEnabled() {
+		return
+	}
+	if p.vramAddr&loopyFineY != loopyFineY {
+		p.vramAddr += 0x1000
+		return
+	}
+
+	p.vramAddr &^= loopyFineY
+	coarseY := (p.vramAddr & loopyCoarseY) >> 5
+	switch coarseY {
+	case 29:
+		// Rows 30 and 31 hold the attribute table, wrap to the next nametable
+		coarseY = 0
+		p.vramAddr ^= loopyNametableY
+	case 31:
+		coarseY = 0
+	default:
+		coarseY++
+	}
+	p.vramAddr = p.vramAddr&^loopyCoarseY | coarseY<<5
+}
+
+func (p *ppu) transferAddressX() {
+	if !p.renderingEnabled() {
+		return
+	}
+	mask := loopyNametableX | loopyCoarseX
+	p.vramAddr = p.vramAddr&^mask | p.tramAddr&mask
+}
+
+func (p *ppu) transferAddressY() {
+	if !p.renderingEnabled() {
+		return
+	}
+	mask := loopyFineY | loopyNametableY | loopyCoarseY
+	p.vramAddr = p.vramAddr&^mask | p.tramAddr&mask
+}
+
+func (p *ppu) loadBackgroundShifters() {
+	p.bgShifterPatternLo = p.bgShifterPatternLo&0xFF00 | uint16(p.bgNextTileLsb)
+	p.bgShifterPatternHi = p.bgShifterPatternHi&0xFF00 | uint16(p.bgNextTileMsb)
+
+	p.bgShifterAttribLo &= 0xFF00
+	if p.bgNextTileAttrib&0x01 != 0 {
+		p.bgShifterAttribLo |= 0x00FF
+	}
+	p.bgShifterAttribHi &= 0xFF00
+	if p.bgNextTileAttrib&0x02 != 0 {
+		p.bgShifterAttribHi |= 0x00FF
+	}
+}
+
+func (p *ppu) updateShifters() {
+	if p.mask&maskRenderBackground == 0 {
+		return
+	}
+	p.bgShifterPatternLo <<= 1
+	p.bgShifterPatternHi <<= 1
+	p.bgShifterAttribLo <<= 1
+	p.bgShifterAttribHi <<= 1
+}
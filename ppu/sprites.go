@@ -0,0 +1,159 @@
+package ppu
+
+// Sprite attribute bits, byte 2 of every OAM entry
+const (
+	spritePalette          byte = 0x03
+	spriteBehindBackground byte = 1 << 5
+	spriteFlipHorizontal   byte = 1 << 6
+	spriteFlipVertical     byte = 1 << 7
+)
+
+const maxSpritesPerScanline = 8
+
+// Private Methods
+
+// evaluateSprites fills secondary OAM with the sprites that intersect the
+// current scanline, they are fetched now and drawn on the next one
+func (p *ppu) evaluateSprites() {
+	for i := range p.secondaryOAM {
+		p.secondaryOAM[i] = 0xFF
+	}
+	p.spriteCount = 0
+	p.spriteZeroOnLine = false
+
+	if p.scanline == preRenderScanline {
+		return
+	}
+
+	n := 0
+	for ; n < 64 && p.spriteCount < maxSpritesPerScanline; n++ {
+		if !p.spriteInRange(p.oam[n*4]) {
+			continue
+		}
+		if n == 0 {
+			p.spriteZeroOnLine = true
+		}
+		copy(p.secondaryOAM[p.spriteCount*4:p.spriteCount*4+4], p.oam[n*4:n*4+4])
+		p.spriteCount++
+	}
+
+	// Once eight sprites are found the hardware keeps looking for a ninth,
+	// but it wrongly increments the byte offset together with the sprite
+	// index, so it ends up comparing tile, attribute and X bytes as Y
+	m := 0
+	for ; n < 64; n++ {
+		if p.spriteInRange(p.oam[n*4+m]) {
+			p.status |= statusSpriteOverflow
+			break
+		}
+		m = (m + 1) & 0x03
+	}
+}
+
+func (p *ppu) spriteInRange(y byte) bool {
+	diff := p.scanline - int(y)
+	return diff >= 0 && diff < p.spriteHeight()
+}
+
+func (p *ppu) spriteHeight() int {
+	if p.control&ctrlSpriteSize != 0 {
+		return 16
+	}
+	return 8
+}
+
+// fetchSprites runs the sprite half of the fetch pattern over dots
+// 257-320, two garbage nametable reads followed by the two pattern planes
+// of each of the eight slots. Unused slots fetch tile $FF.
+func (p *ppu) fetchSprites() {
+	slot := (p.cycle - 257) / 8
+
+	switch (p.cycle - 257) % 8 {
+	case 0, 2:
+		p.PPURead(0x2000|p.vramAddr&0x0FFF, false)
+	case 4:
+		p.spritePatternLo[slot] = p.spritePattern(slot, p.PPURead(p.spriteTileAddr(slot), false))
+	case 6:
+		p.spritePatternHi[slot] = p.spritePattern(slot, p.PPURead(p.spriteTileAddr(slot)+8, false))
+		p.spriteAttrib[slot] = p.secondaryOAM[slot*4+2]
+		p.spriteX[slot] = p.secondaryOAM[slot*4+3]
+	}
+}
+
+func (p *ppu) spriteTileAddr(slot int) uint16 {
+	y := p.secondaryOAM[slot*4]
+	tile := uint16(p.secondaryOAM[slot*4+1])
+	attrib := p.secondaryOAM[slot*4+2]
+
+	row := uint16(p.scanline-int(y)) & uint16(p.spriteHeight()-1)
+	if slot >= p.spriteCount {
+		row = 0
+	}
+	if attrib&spriteFlipVertical != 0 {
+		row = uint16(p.spriteHeight()-1) - row
+	}
+
+	if p.spriteHeight() == 16 {
+		// 8x16 sprites pick the table with bit 0 of the tile index
+		table := (tile & 0x01) << 12
+		tile &= 0xFE
+		if row >= 8 {
+			tile++
+			row -= 8
+		}
+		return table | tile<<4 | row
+	}
+
+	var table uint16
+	if p.control&ctrlPatternSprite != 0 {
+		table = 0x1000
+	}
+	return table | tile<<4 | row
+}
+
+// spritePattern stores pattern bits with the leftmost pixel in bit 7,
+// applying horizontal flip and blanking unused slots
+func (p *ppu) spritePattern(slot int, data byte) byte {
+	if slot >= p.spriteCount {
+		return 0x00
+	}
+	if p.secondaryOAM[slot*4+2]&spriteFlipHorizontal != 0 {
+		data = flipByte(data)
+	}
+	return data
+}
+
+// spritePixel returns the first opaque sprite pixel at the current dot,
+// along with its attributes and whether it belongs to sprite 0
+func (p *ppu) spritePixel() (pixel byte, attrib byte, spriteZero bool) {
+	if p.mask&maskRenderSprites == 0 || (p.cycle <= 8 && p.mask&maskRenderSpritesLeft == 0) {
+		return 0, 0, false
+	}
+
+	x := p.cycle - 1
+	for i := 0; i < p.spriteCount; i++ {
+		column := x - int(p.spriteX[i])
+		if column < 0 || column > 7 {
+			continue
+		}
+		bit := byte(0x80) >> column
+		pixel = 0
+		if p.spritePatternLo[i]&bit != 0 {
+			pixel |= 0x01
+		}
+		if p.spritePatternHi[i]&bit != 0 {
+			pixel |= 0x02
+		}
+		if pixel != 0 {
+			return pixel, p.spriteAttrib[i], i == 0 && p.spriteZeroOnLine
+		}
+	}
+	return 0, 0, false
+}
+
+func flipByte(b byte) byte {
+	b = (b&0xF0)>>4 | (b&0x0F)<<4
+	b = (b&0xCC)>>2 | (b&0x33)<<2
+	b = (b&0xAA)>>1 | (b&0x55)<<1
+	return b
+}